@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/wongak/nand2tetris/pkg/hack/assembly/language"
+)
+
+var (
+	verbose bool
+)
+
+func main() {
+	flag.BoolVar(&verbose, "v", false, "verbose")
+	flag.Parse()
+
+	if len(flag.Args()) == 0 {
+		fmt.Println("expecting at least one argument. asm file(s) to assemble")
+		os.Exit(1)
+	}
+
+	failed := false
+	for _, inputFileName := range flag.Args() {
+		err := assembleFile(inputFileName)
+		if err != nil {
+			fmt.Println(err)
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func outputFileName(inputFileName string) string {
+	fileDir := filepath.Dir(inputFileName)
+	fileBase := filepath.Base(inputFileName)
+	parts := strings.Split(fileBase, ".")
+	if len(parts) == 1 {
+		parts = append(parts, "hack")
+	} else {
+		parts[len(parts)-1] = "hack"
+	}
+	return filepath.Join(fileDir, strings.Join(parts, "."))
+}
+
+// assembleFile assembles the given file and writes the .hack file next to it.
+// The output is only written if the whole file assembles.
+func assembleFile(inputFileName string) error {
+	in, err := os.Open(inputFileName)
+	if err != nil {
+		return fmt.Errorf("error opening asm file: %v", err)
+	}
+	defer in.Close()
+
+	if verbose {
+		fmt.Printf("parsing %s...\n", inputFileName)
+	}
+	p := language.NewFileParser(in, inputFileName)
+	err = p.Run()
+	if err != nil {
+		return err
+	}
+
+	buf := bytes.NewBuffer(nil)
+	err = language.Assemble(language.NewSymbolTable(), p.Tree(), buf)
+	if err != nil {
+		return err
+	}
+
+	outFileName := outputFileName(inputFileName)
+	if verbose {
+		fmt.Printf("writing %s\n", outFileName)
+	}
+	err = os.WriteFile(outFileName, buf.Bytes(), 0644)
+	if err != nil {
+		return fmt.Errorf("error writing output file: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/wongak/nand2tetris/pkg/hack/cpu"
+	"github.com/wongak/nand2tetris/pkg/hack/jack"
+	"github.com/wongak/nand2tetris/pkg/hack/vm/language"
+	"github.com/wongak/nand2tetris/pkg/hack/vm/translator"
+)
+
+var (
+	emit    string
+	keep    bool
+	check   bool
+	shared  bool
+	run     int
+	screen  string
+	verbose bool
+)
+
+func main() {
+	flag.StringVar(&emit, "emit", "hack", "stop after the given stage: vm, asm or hack")
+	flag.BoolVar(&keep, "keep", false, "keep intermediate .vm and .asm files")
+	flag.BoolVar(&check, "check", true, "check labels, function calls and argument counts before translation. calls to undefined functions, e.g. of the OS, are warnings")
+	flag.BoolVar(&shared, "shared", false, "use shared call, return and comparison routines to reduce code size")
+	flag.IntVar(&run, "run", 0, "run the program in the CPU emulator for at most the given number of cycles")
+	flag.StringVar(&screen, "screen", "", "write the screen after -run to the given .png or .pbm file")
+	flag.BoolVar(&verbose, "v", false, "verbose")
+	flag.Parse()
+
+	if emit != "vm" && emit != "asm" && emit != "hack" {
+		fmt.Printf("invalid -emit %s. expect vm, asm or hack\n", emit)
+		os.Exit(1)
+	}
+	if run > 0 && emit != "hack" {
+		fmt.Println("-run requires -emit=hack")
+		os.Exit(1)
+	}
+	if screen != "" && run == 0 {
+		fmt.Println("-screen requires -run")
+		os.Exit(1)
+	}
+	if ext := filepath.Ext(screen); screen != "" && ext != ".png" && ext != ".pbm" {
+		fmt.Printf("invalid -screen %s. expect a .png or .pbm file\n", screen)
+		os.Exit(1)
+	}
+	if len(flag.Args()) == 0 {
+		fmt.Println("expecting at least one argument. directory or jack/vm files to compile")
+		os.Exit(1)
+	}
+
+	inputs, outBase, err := resolveInputs(flag.Args())
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	err = build(inputs, outBase)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+func withExt(fileName, ext string) string {
+	return strings.TrimSuffix(fileName, filepath.Ext(fileName)) + ext
+}
+
+// resolveInputs collects the .jack and .vm files of the arguments in
+// translation order. Compiled .vm files of given .jack files are skipped.
+// The output base name is derived from the directory or a single file.
+func resolveInputs(args []string) ([]string, string, error) {
+	files := make([]string, 0)
+	var outBase string
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil {
+			return nil, "", fmt.Errorf("error on stat input: %v", err)
+		}
+		if !info.IsDir() {
+			files = append(files, arg)
+			continue
+		}
+		abs, err := filepath.Abs(arg)
+		if err != nil {
+			return nil, "", fmt.Errorf("error resolving out path: %v", err)
+		}
+		if outBase == "" {
+			outBase = filepath.Join(arg, filepath.Base(abs))
+		}
+		entries, err := os.ReadDir(arg)
+		if err != nil {
+			return nil, "", fmt.Errorf("error reading dir: %v", err)
+		}
+		for _, e := range entries {
+			if e.IsDir() {
+				continue
+			}
+			files = append(files, filepath.Join(arg, e.Name()))
+		}
+	}
+
+	jackFiles := make(map[string]bool)
+	for _, f := range files {
+		if filepath.Ext(f) == ".jack" {
+			jackFiles[withExt(f, "")] = true
+		}
+	}
+	inputs := make([]string, 0)
+	for _, f := range files {
+		switch filepath.Ext(f) {
+		case ".jack":
+			inputs = append(inputs, f)
+		case ".vm":
+			if !jackFiles[withExt(f, "")] {
+				inputs = append(inputs, f)
+			}
+		}
+	}
+	if len(inputs) == 0 {
+		return nil, "", fmt.Errorf("no jack or vm files found")
+	}
+	translator.SortFiles(inputs)
+
+	if outBase == "" {
+		if len(inputs) == 1 {
+			outBase = withExt(inputs[0], "")
+		} else {
+			abs, err := filepath.Abs(filepath.Dir(inputs[0]))
+			if err != nil {
+				return nil, "", fmt.Errorf("error resolving out path: %v", err)
+			}
+			outBase = filepath.Join(filepath.Dir(inputs[0]), filepath.Base(abs))
+		}
+	}
+	return inputs, outBase, nil
+}
+
+func writeFile(fileName string, content []byte) error {
+	if verbose {
+		fmt.Printf("writing %s\n", fileName)
+	}
+	err := os.WriteFile(fileName, content, 0644)
+	if err != nil {
+		return fmt.Errorf("error writing output file: %v", err)
+	}
+	return nil
+}
+
+// compileJack compiles the Jack file and registers it in the translator
+func compileJack(tr *translator.Translator, fileName string) ([]language.Command, error) {
+	in, err := os.Open(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("error opening jack file: %v", err)
+	}
+	defer in.Close()
+
+	if verbose {
+		fmt.Printf("compiling %s...\n", fileName)
+	}
+	p := jack.NewParser(in, fileName)
+	err = p.Run()
+	if err != nil {
+		return nil, err
+	}
+	c := jack.NewCompiler(p.Tree())
+	err = c.Run(tr.SymbolTable())
+	if err != nil {
+		return nil, err
+	}
+	return c.Tree(), nil
+}
+
+// parse compiles a Jack file or parses a VM file and registers it in the
+// translator. Compiled Jack files are written as VM code on request.
+func parse(tr *translator.Translator, fileName string) ([]language.Command, error) {
+	if filepath.Ext(fileName) != ".jack" {
+		return tr.ParseFile(fileName)
+	}
+	cmds, err := compileJack(tr, fileName)
+	if err != nil {
+		return nil, err
+	}
+	if emit == "vm" || keep {
+		buf := bytes.NewBuffer(nil)
+		err = language.WriteCode(buf, cmds)
+		if err != nil {
+			return nil, err
+		}
+		err = writeFile(withExt(fileName, ".vm"), buf.Bytes())
+		if err != nil {
+			return nil, err
+		}
+	}
+	return cmds, nil
+}
+
+func build(inputs []string, outBase string) error {
+	tr := translator.New()
+	if shared {
+		tr.UseSharedRoutines()
+	}
+
+	// Jack to VM
+	if emit == "vm" {
+		for _, fileName := range inputs {
+			_, err := parse(tr, fileName)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// VM to assembly
+	err := tr.Build(inputs, translator.Options{
+		Check: check,
+		Warn:  os.Stdout,
+		Parse: func(fileName string) ([]language.Command, error) {
+			return parse(tr, fileName)
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if emit == "asm" || keep {
+		out := bytes.NewBuffer(nil)
+		err = tr.WriteAsm(out)
+		if err != nil {
+			return err
+		}
+		err = writeFile(outBase+".asm", out.Bytes())
+		if err != nil {
+			return err
+		}
+	}
+	if emit == "asm" {
+		return nil
+	}
+
+	// assembly to machine code
+	hack := bytes.NewBuffer(nil)
+	err = tr.WriteHack(hack)
+	if err != nil {
+		return err
+	}
+	err = writeFile(outBase+".hack", hack.Bytes())
+	if err != nil {
+		return err
+	}
+
+	if run > 0 {
+		return emulate(hack.Bytes())
+	}
+	return nil
+}
+
+// emulate runs the machine code and prints the state of the stack
+func emulate(hack []byte) error {
+	m := cpu.NewMachine()
+	err := m.Load(bytes.NewReader(hack))
+	if err != nil {
+		return err
+	}
+	cycles, err := m.Run(run)
+	if err != nil {
+		return fmt.Errorf("emulation error after %d cycles: %v", cycles, err)
+	}
+	if m.Halted() {
+		fmt.Printf("halted after %d cycles\n", cycles)
+	} else {
+		fmt.Printf("stopped after %d cycles at PC %d\n", cycles, m.PC)
+	}
+	sp := uint16(m.RAM[0])
+	fmt.Printf("SP: %d LCL: %d ARG: %d THIS: %d THAT: %d\n", sp, m.RAM[1], m.RAM[2], m.RAM[3], m.RAM[4])
+	if sp > 256 && sp < cpu.Screen {
+		fmt.Printf("top of stack: %d\n", m.RAM[sp-1])
+	}
+	if screen != "" {
+		return writeScreen(m)
+	}
+	return nil
+}
+
+// writeScreen writes the screen as PNG or PBM by the file extension
+func writeScreen(m *cpu.Machine) error {
+	buf := bytes.NewBuffer(nil)
+	var err error
+	if filepath.Ext(screen) == ".pbm" {
+		err = m.WritePBM(buf)
+	} else {
+		err = m.WritePNG(buf)
+	}
+	if err != nil {
+		return err
+	}
+	return writeFile(screen, buf.Bytes())
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBuildWithoutOS(t *testing.T) {
+	emit, check = "hack", true
+	dir := filepath.Join(t.TempDir(), "Prog")
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(dir, "Main.jack"), []byte(`class Main {
+	function void main() {
+		var int x;
+		let x = 3 * 4;
+		return;
+	}
+}
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inputs, outBase, err := resolveInputs([]string{dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = build(inputs, outBase)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = os.Stat(filepath.Join(dir, "Prog.hack"))
+	if err != nil {
+		t.Errorf("expect hack file: %v", err)
+	}
+}
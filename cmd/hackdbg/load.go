@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/wongak/nand2tetris/pkg/hack/assembly/disassembler"
+	asm "github.com/wongak/nand2tetris/pkg/hack/assembly/language"
+	"github.com/wongak/nand2tetris/pkg/hack/debugger"
+	"github.com/wongak/nand2tetris/pkg/hack/vm/translator"
+)
+
+// load returns the program of the arguments: a single .hack or .asm
+// file, or VM files and directories of VM files
+func load(args []string) (*debugger.Program, error) {
+	if len(args) == 1 {
+		switch filepath.Ext(args[0]) {
+		case ".hack":
+			return loadHack(args[0])
+		case ".asm":
+			return loadAsm(args[0])
+		}
+	}
+	fileNames, err := translator.Inputs(args)
+	if err != nil {
+		return nil, err
+	}
+	return loadVM(fileNames)
+}
+
+// loadHack disassembles the machine code for the synthesized labels
+func loadHack(fileName string) (*debugger.Program, error) {
+	in, err := os.Open(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("error opening hack file: %v", err)
+	}
+	defer in.Close()
+
+	words, err := disassembler.Read(in)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", fileName, err)
+	}
+	cmds, err := disassembler.Disassemble(words)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", fileName, err)
+	}
+	return debugger.Assembled(cmds)
+}
+
+func loadAsm(fileName string) (*debugger.Program, error) {
+	in, err := os.Open(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("error opening asm file: %v", err)
+	}
+	defer in.Close()
+
+	p := asm.NewFileParser(in, fileName)
+	err = p.Run()
+	if err != nil {
+		return nil, err
+	}
+	return debugger.Assembled(p.Tree())
+}
+
+// loadVM translates the VM files with the source map
+func loadVM(fileNames []string) (*debugger.Program, error) {
+	tr := translator.New()
+	err := tr.Build(fileNames, translator.Options{
+		Headless: headless,
+		Check:    true,
+		Warn:     os.Stdout,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return debugger.Translated(tr)
+}
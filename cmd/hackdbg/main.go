@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/wongak/nand2tetris/pkg/hack/cpu"
+	"github.com/wongak/nand2tetris/pkg/hack/debugger"
+)
+
+var (
+	headless  bool
+	maxCycles int
+)
+
+const help = `commands:
+  b <loc>       set a breakpoint. loc is a ROM address, a label or file:line
+  d <loc>       delete the breakpoint
+  bl            list the breakpoints
+  s [n]         step n instructions
+  n [n]         step n VM commands
+  c             continue to the next breakpoint
+  r             reset the machine
+  l             show the current location
+  regs          show the registers
+  stack         show the stack
+  frame         show the frame of the current function
+  statics       show the static variables
+  x <addr> [n]  show n words of RAM from addr
+  q             quit
+`
+
+func main() {
+	flag.BoolVar(&headless, "hl", false, "headless mode: translate VM files without bootstrap code")
+	flag.IntVar(&maxCycles, "max", 10000000, "maximum number of cycles per run command")
+	flag.Parse()
+
+	if len(flag.Args()) == 0 {
+		fmt.Println("expecting at least one argument. hack or asm file, or directory or vm files to debug")
+		os.Exit(1)
+	}
+
+	p, err := load(flag.Args())
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	d, err := debugger.New(p)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	s := &session{d: d, out: os.Stdout}
+	s.repl(os.Stdin)
+}
+
+// session executes the debugger commands
+type session struct {
+	d   *debugger.Debugger
+	out io.Writer
+}
+
+// repl reads and executes commands until quit or the end of the input.
+// An empty line repeats the last command.
+func (s *session) repl(r io.Reader) {
+	sc := bufio.NewScanner(r)
+	var last string
+	s.where()
+	for {
+		fmt.Fprint(s.out, "(hackdbg) ")
+		if !sc.Scan() {
+			fmt.Fprintln(s.out)
+			return
+		}
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			line = last
+		}
+		last = line
+		if !s.exec(line) {
+			return
+		}
+	}
+}
+
+// exec executes the command line and returns false on quit
+func (s *session) exec(line string) bool {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return true
+	}
+	var err error
+	switch cmd, args := fields[0], fields[1:]; cmd {
+	case "q", "quit":
+		return false
+	case "h", "help":
+		fmt.Fprint(s.out, help)
+	case "b", "break":
+		err = s.breakpoint(args, true)
+	case "d", "delete":
+		err = s.breakpoint(args, false)
+	case "bl":
+		for _, addr := range s.d.Breakpoints() {
+			s.instruction(addr)
+		}
+	case "s", "step":
+		err = s.step(args, s.d.Step)
+	case "n", "next":
+		err = s.step(args, func() (debugger.Stop, error) {
+			return s.d.StepVM(maxCycles)
+		})
+	case "c", "continue":
+		err = s.stopped(s.d.Continue(maxCycles))
+	case "r", "reset":
+		s.d.Reset()
+		s.where()
+	case "l":
+		s.where()
+	case "regs":
+		m := s.d.Machine
+		fmt.Fprintf(s.out, "PC: %d A: %d D: %d\n", m.PC, m.A, m.D)
+		fmt.Fprintf(s.out, "SP: %d LCL: %d ARG: %d THIS: %d THAT: %d\n", m.RAM[0], m.RAM[1], m.RAM[2], m.RAM[3], m.RAM[4])
+	case "stack":
+		fmt.Fprintf(s.out, "%v\n", s.d.Stack())
+	case "frame":
+		s.frame()
+	case "statics":
+		for _, st := range s.d.Statics() {
+			fmt.Fprintf(s.out, "%s (%d): %d\n", st.Name, st.Addr, s.d.Machine.RAM[st.Addr])
+		}
+	case "x":
+		err = s.examine(args)
+	default:
+		err = fmt.Errorf("unknown command %s. try help", cmd)
+	}
+	if err != nil {
+		fmt.Fprintln(s.out, err)
+	}
+	return true
+}
+
+func (s *session) breakpoint(args []string, set bool) error {
+	if len(args) != 1 {
+		return fmt.Errorf("expect a location")
+	}
+	addr, err := s.d.Resolve(args[0])
+	if err != nil {
+		return err
+	}
+	if set {
+		s.d.Break(addr)
+	} else {
+		s.d.Clear(addr)
+	}
+	s.instruction(addr)
+	return nil
+}
+
+// step runs the step function n times or up to a stop other than stepped
+func (s *session) step(args []string, step func() (debugger.Stop, error)) error {
+	n := 1
+	if len(args) > 0 {
+		var err error
+		n, err = strconv.Atoi(args[0])
+		if err != nil || n < 1 {
+			return fmt.Errorf("invalid count %s", args[0])
+		}
+	}
+	stop, err := debugger.Stepped, error(nil)
+	for i := 0; i < n && stop == debugger.Stepped && err == nil; i++ {
+		stop, err = step()
+	}
+	return s.stopped(stop, err)
+}
+
+func (s *session) stopped(stop debugger.Stop, err error) error {
+	if err != nil {
+		return err
+	}
+	if stop != debugger.Stepped {
+		fmt.Fprintf(s.out, "%s after %d cycles\n", stop, s.d.Machine.Cycles())
+	}
+	s.where()
+	return nil
+}
+
+// where prints the instruction at the PC
+func (s *session) where() {
+	s.instruction(int(s.d.Machine.PC))
+}
+
+// instruction prints the address with its location, the instruction
+// and the VM command it was translated from
+func (s *session) instruction(addr int) {
+	line := fmt.Sprintf("%5d %-20s %s", addr, s.d.Location(addr), s.d.Instruction(addr))
+	if src, ok := s.d.Source(addr); ok && src.Code != "" {
+		line = fmt.Sprintf("%-40s // %s", line, src.Code)
+		if src.File != "" {
+			line += fmt.Sprintf(" (%s:%d)", src.File, src.Line)
+		}
+	}
+	fmt.Fprintln(s.out, strings.TrimRight(line, " "))
+}
+
+func (s *session) frame() {
+	f := s.d.Frame()
+	if f.Function != "" {
+		fmt.Fprintf(s.out, "function %s\n", f.Function)
+	}
+	fmt.Fprintf(s.out, "LCL: %d ARG: %d THIS: %d THAT: %d\n", f.LCL, f.ARG, f.THIS, f.THAT)
+	fmt.Fprintf(s.out, "return: %d %s\n", f.ReturnAddr, s.d.Location(int(f.ReturnAddr)))
+	fmt.Fprintf(s.out, "args:   %v\n", f.Args)
+	fmt.Fprintf(s.out, "locals: %v\n", f.Locals)
+	fmt.Fprintf(s.out, "stack:  %v\n", f.Stack)
+}
+
+func (s *session) examine(args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return fmt.Errorf("expect an address and an optional count")
+	}
+	addr, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid address %s", args[0])
+	}
+	n := 1
+	if len(args) == 2 {
+		n, err = strconv.Atoi(args[1])
+		if err != nil || n < 1 {
+			return fmt.Errorf("invalid count %s", args[1])
+		}
+	}
+	if addr < 0 || addr+n > cpu.MemorySize {
+		return fmt.Errorf("address out of RAM")
+	}
+	for i := addr; i < addr+n; i++ {
+		fmt.Fprintf(s.out, "%5d: %d\n", i, s.d.Machine.RAM[i])
+	}
+	return nil
+}
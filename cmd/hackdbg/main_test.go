@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wongak/nand2tetris/pkg/hack/debugger"
+)
+
+func TestSession(t *testing.T) {
+	maxCycles = 100000
+	p, err := load([]string{filepath.Join("..", "..", "pkg", "hack", "vm", "translator", "testdata", "StaticsTest")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := debugger.New(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := bytes.NewBuffer(nil)
+	s := &session{d: d, out: out}
+	s.repl(strings.NewReader("b Class1.vm:3\nc\nframe\nd Class1.vm:3\nc\nstatics\nq\n"))
+
+	for _, expect := range []string{
+		"breakpoint after",
+		"Class1.set           @0",
+		"// push argument 0 (",
+		"function Class1.set\n",
+		"args:   [6 8]\n",
+		"halted after",
+		"Class2.0 (18): 23\n",
+	} {
+		if !strings.Contains(out.String(), expect) {
+			t.Errorf("expect output to contain %q, got\n%s", expect, out.String())
+		}
+	}
+}
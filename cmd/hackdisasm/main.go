@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/wongak/nand2tetris/pkg/hack/assembly/disassembler"
+	"github.com/wongak/nand2tetris/pkg/hack/assembly/language"
+)
+
+var output string
+
+func main() {
+	flag.StringVar(&output, "o", "", "output file. defaults to stdout")
+	flag.Parse()
+
+	if len(flag.Args()) != 1 {
+		fmt.Println("expecting one argument. hack file to disassemble")
+		os.Exit(1)
+	}
+
+	var wr io.Writer = os.Stdout
+	if output != "" {
+		out, err := os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+		if err != nil {
+			fmt.Printf("error opening output file: %v\n", err)
+			os.Exit(1)
+		}
+		defer out.Close()
+		wr = out
+	}
+
+	err := disassembleFile(wr, flag.Arg(0))
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+func disassembleFile(wr io.Writer, fileName string) error {
+	in, err := os.Open(fileName)
+	if err != nil {
+		return fmt.Errorf("error opening hack file: %v", err)
+	}
+	defer in.Close()
+
+	words, err := disassembler.Read(in)
+	if err != nil {
+		return fmt.Errorf("%s: %v", fileName, err)
+	}
+	cmds, err := disassembler.Disassemble(words)
+	if err != nil {
+		return fmt.Errorf("%s: %v", fileName, err)
+	}
+	return language.WriteCode(wr, cmds)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/wongak/nand2tetris/pkg/hack/cpu"
+)
+
+// keyCtrlC quits the program
+const keyCtrlC = 0x03
+
+// escapeKeys holds the Hack key codes of the terminal escape sequences
+// after ESC
+var escapeKeys = map[string]int16{
+	"[A": cpu.KeyUp, "[B": cpu.KeyDown, "[C": cpu.KeyRight, "[D": cpu.KeyLeft,
+	"[H": cpu.KeyHome, "[F": cpu.KeyEnd, "OH": cpu.KeyHome, "OF": cpu.KeyEnd,
+	"[1~": cpu.KeyHome, "[4~": cpu.KeyEnd, "[7~": cpu.KeyHome, "[8~": cpu.KeyEnd,
+	"[2~": cpu.KeyInsert, "[3~": cpu.KeyDelete,
+	"[5~": cpu.KeyPageUp, "[6~": cpu.KeyPageDown,
+	"OP": cpu.KeyF1, "OQ": cpu.KeyF1 + 1, "OR": cpu.KeyF1 + 2, "OS": cpu.KeyF1 + 3,
+	"[15~": cpu.KeyF1 + 4, "[17~": cpu.KeyF1 + 5, "[18~": cpu.KeyF1 + 6, "[19~": cpu.KeyF1 + 7,
+	"[20~": cpu.KeyF1 + 8, "[21~": cpu.KeyF1 + 9, "[23~": cpu.KeyF1 + 10, "[24~": cpu.KeyF1 + 11,
+}
+
+// parseKeys returns the Hack key codes of the terminal input read at
+// once. ESC alone is the escape key, unknown sequences are skipped. quit
+// is true on Ctrl-C.
+func parseKeys(input []byte) (codes []int16, quit bool) {
+	for len(input) > 0 {
+		b := input[0]
+		input = input[1:]
+		switch {
+		case b == keyCtrlC:
+			return codes, true
+		case b == 0x1b:
+			var code int16
+			code, input = parseEscape(input)
+			if code != 0 {
+				codes = append(codes, code)
+			}
+		default:
+			if code, ok := cpu.KeyCode(rune(b)); ok {
+				codes = append(codes, code)
+			}
+		}
+	}
+	return codes, false
+}
+
+// parseEscape returns the key code of the escape sequence at the start
+// of the input and the remaining input
+func parseEscape(input []byte) (int16, []byte) {
+	if len(input) == 0 || (input[0] != '[' && input[0] != 'O') {
+		return cpu.KeyEsc, input
+	}
+	// a sequence ends with a letter or ~
+	end := strings.IndexFunc(string(input[1:]), func(r rune) bool {
+		return r == '~' || (r >= 'A' && r <= 'Z') || (r >= 'a' && r <= 'z')
+	})
+	if end < 0 {
+		return 0, nil
+	}
+	seq := string(input[:end+2])
+	return escapeKeys[seq], input[end+2:]
+}
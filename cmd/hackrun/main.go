@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	asm "github.com/wongak/nand2tetris/pkg/hack/assembly/language"
+	"github.com/wongak/nand2tetris/pkg/hack/cpu"
+)
+
+var (
+	mode  string
+	scale int
+	fps   int
+	speed int
+	hold  time.Duration
+)
+
+func main() {
+	flag.StringVar(&mode, "mode", "braille", "render 2x4 pixels per character with braille or 1x2 with half blocks: braille or half")
+	flag.IntVar(&scale, "scale", 1, "scale the screen down by the factor to fit small terminals")
+	flag.IntVar(&fps, "fps", 30, "frames per second")
+	flag.IntVar(&speed, "speed", 2000000, "instructions per second")
+	flag.DurationVar(&hold, "hold", 150*time.Millisecond, "time a key stays pressed without key repeat")
+	flag.Parse()
+
+	if len(flag.Args()) != 1 {
+		fmt.Println("expecting one argument. hack or asm file to run")
+		os.Exit(1)
+	}
+	if mode != "braille" && mode != "half" {
+		fmt.Printf("invalid -mode %s. expect braille or half\n", mode)
+		os.Exit(1)
+	}
+	if scale < 1 || fps < 1 || speed < fps {
+		fmt.Println("-scale and -fps must be positive, -speed at least -fps")
+		os.Exit(1)
+	}
+
+	m, err := load(flag.Arg(0))
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	restore, err := rawMode()
+	if err != nil {
+		fmt.Printf("error switching terminal to raw mode: %v\n", err)
+		os.Exit(1)
+	}
+	err = run(m, os.Stdin, os.Stdout)
+	restore()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+// load loads a .hack or .asm file into a new machine
+func load(fileName string) (*cpu.Machine, error) {
+	in, err := os.Open(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("error opening input file: %v", err)
+	}
+	defer in.Close()
+
+	m := cpu.NewMachine()
+	switch filepath.Ext(fileName) {
+	case ".hack":
+		err = m.Load(in)
+	case ".asm":
+		p := asm.NewFileParser(in, fileName)
+		err = p.Run()
+		if err != nil {
+			return nil, err
+		}
+		buf := bytes.NewBuffer(nil)
+		err = asm.Assemble(asm.NewSymbolTable(), p.Tree(), buf)
+		if err != nil {
+			return nil, err
+		}
+		err = m.Load(buf)
+	default:
+		return nil, fmt.Errorf("invalid input %s. expect a .hack or .asm file", fileName)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", fileName, err)
+	}
+	return m, nil
+}
+
+// stty runs stty on the terminal of stdin
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+// rawMode disables line buffering and echo of the terminal. It returns
+// the function restoring the previous state.
+func rawMode() (func(), error) {
+	state, err := stty("-g")
+	if err != nil {
+		return nil, fmt.Errorf("stdin is not a terminal: %v", err)
+	}
+	_, err = stty("raw", "-echo")
+	if err != nil {
+		return nil, err
+	}
+	return func() {
+		stty(state)
+	}, nil
+}
+
+// display draws the frames, rewriting only the changed lines
+type display struct {
+	out  *bufio.Writer
+	prev []string
+}
+
+func (d *display) draw(lines []string, status string) error {
+	for i, line := range lines {
+		if i < len(d.prev) && d.prev[i] == line {
+			continue
+		}
+		fmt.Fprintf(d.out, "\x1b[%d;1H%s", i+1, line)
+	}
+	fmt.Fprintf(d.out, "\x1b[%d;1H\x1b[K%s", len(lines)+1, status)
+	d.prev = lines
+	return d.out.Flush()
+}
+
+// run executes the program at the given speed, draws the screen on every
+// frame and feeds the keys typed into the keyboard memory map until
+// Ctrl-C. Terminals do not report releasing a key, a key is released
+// after the hold time unless repeated.
+func run(m *cpu.Machine, in io.Reader, out io.Writer) error {
+	keys := make(chan []byte)
+	go func() {
+		buf := make([]byte, 64)
+		for {
+			n, err := in.Read(buf)
+			if err != nil {
+				close(keys)
+				return
+			}
+			input := make([]byte, n)
+			copy(input, buf[:n])
+			keys <- input
+		}
+	}()
+
+	d := &display{out: bufio.NewWriter(out)}
+	// clear the screen and hide the cursor
+	fmt.Fprint(d.out, "\x1b[2J\x1b[?25l")
+	defer func() {
+		fmt.Fprint(d.out, "\x1b[?25h\r\n")
+		d.out.Flush()
+	}()
+
+	ticker := time.NewTicker(time.Second / time.Duration(fps))
+	defer ticker.Stop()
+	var release time.Time
+	for {
+		select {
+		case input, ok := <-keys:
+			if !ok {
+				return nil
+			}
+			codes, quit := parseKeys(input)
+			if quit {
+				return nil
+			}
+			if len(codes) > 0 {
+				m.RAM[cpu.Keyboard] = codes[len(codes)-1]
+				release = time.Now().Add(hold)
+			}
+		case now := <-ticker.C:
+			if m.RAM[cpu.Keyboard] != 0 && now.After(release) {
+				m.RAM[cpu.Keyboard] = 0
+			}
+			_, err := m.Run(speed / fps)
+			if err != nil {
+				return fmt.Errorf("emulation error after %d cycles: %v", m.Cycles(), err)
+			}
+			lines, err := render(m, mode, scale)
+			if err != nil {
+				return err
+			}
+			status := fmt.Sprintf("cycles: %d  KBD: %d", m.Cycles(), m.RAM[cpu.Keyboard])
+			if m.Halted() {
+				status += "  halted"
+			}
+			err = d.draw(lines, status+"  Ctrl-C quits")
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
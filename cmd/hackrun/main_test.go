@@ -0,0 +1,93 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/wongak/nand2tetris/pkg/hack/cpu"
+)
+
+func TestRender(t *testing.T) {
+	m := cpu.NewMachine()
+	// pixels 0,0 and 1,3 in the first word, the full row 5 of the
+	// second word and the last pixel
+	m.RAM[cpu.Screen] = 0x0003
+	m.RAM[cpu.Screen+3*32] = 0x0002
+	m.RAM[cpu.Screen+5*32+1] = -1
+	m.RAM[cpu.Screen+255*32+31] = -0x8000
+
+	for _, c := range []struct {
+		mode   string
+		scale  int
+		width  int
+		height int
+		expect map[[2]int]rune
+	}{
+		{"braille", 1, 256, 64, map[[2]int]rune{
+			{0, 0}:    0x2800 | 0x01 | 0x08 | 0x80,
+			{1, 0}:    0x2800,
+			{8, 1}:    0x2800 | 0x02 | 0x10,
+			{255, 63}: 0x2800 | 0x80,
+		}},
+		{"half", 1, 512, 128, map[[2]int]rune{
+			{0, 0}:     '▀',
+			{1, 0}:     '▀',
+			{1, 1}:     '▄',
+			{2, 0}:     ' ',
+			{16, 2}:    '▄',
+			{511, 127}: '▄',
+		}},
+		{"half", 2, 256, 64, map[[2]int]rune{
+			{0, 0}:    '█',
+			{8, 1}:    '▀',
+			{255, 63}: '▄',
+		}},
+	} {
+		lines, err := render(m, c.mode, c.scale)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(lines) != c.height {
+			t.Fatalf("%s/%d: expect %d lines, got %d", c.mode, c.scale, c.height, len(lines))
+		}
+		for _, line := range lines {
+			if utf8.RuneCountInString(line) != c.width {
+				t.Fatalf("%s/%d: expect %d characters per line, got %d", c.mode, c.scale, c.width, utf8.RuneCountInString(line))
+			}
+		}
+		for pos, expect := range c.expect {
+			ch := []rune(lines[pos[1]])[pos[0]]
+			if ch != expect {
+				t.Errorf("%s/%d: expect %q at %v, got %q", c.mode, c.scale, expect, pos, ch)
+			}
+		}
+	}
+
+	if _, err := render(m, "ascii", 1); err == nil {
+		t.Error("expect error on invalid mode")
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	for _, c := range []struct {
+		input  string
+		expect []int16
+		quit   bool
+	}{
+		{"a", []int16{'a'}, false},
+		{"Hi\r", []int16{'H', 'i', cpu.KeyNewline}, false},
+		{"\x7f", []int16{cpu.KeyBackspace}, false},
+		{"\x1b", []int16{cpu.KeyEsc}, false},
+		{"\x1b[A\x1b[D", []int16{cpu.KeyUp, cpu.KeyLeft}, false},
+		{"\x1b[3~x", []int16{cpu.KeyDelete, 'x'}, false},
+		{"\x1bOP\x1b[24~", []int16{cpu.KeyF1, cpu.KeyF1 + 11}, false},
+		{"\x1b[1;5A", nil, false},
+		{"q\x03z", []int16{'q'}, true},
+	} {
+		codes, quit := parseKeys([]byte(c.input))
+		if !reflect.DeepEqual(codes, c.expect) || quit != c.quit {
+			t.Errorf("%q: expect %v quit %t, got %v quit %t", c.input, c.expect, c.quit, codes, quit)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/wongak/nand2tetris/pkg/hack/cpu"
+)
+
+// brailleDots holds the bits of the braille dots by column and row of
+// the 2x4 pixels of a character
+var brailleDots = [2][4]rune{
+	{0x01, 0x02, 0x04, 0x40},
+	{0x08, 0x10, 0x20, 0x80},
+}
+
+// screen samples the screen memory map scaled down by an integer factor
+type screen struct {
+	m     *cpu.Machine
+	scale int
+}
+
+// width returns the number of scaled pixels per row
+func (s screen) width() int {
+	return (cpu.ScreenWidth + s.scale - 1) / s.scale
+}
+
+// height returns the number of scaled pixel rows
+func (s screen) height() int {
+	return (cpu.ScreenHeight + s.scale - 1) / s.scale
+}
+
+// pixel returns true if any screen pixel of the scaled pixel is black
+func (s screen) pixel(x, y int) bool {
+	for dy := 0; dy < s.scale; dy++ {
+		for dx := 0; dx < s.scale; dx++ {
+			if s.m.Pixel(x*s.scale+dx, y*s.scale+dy) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// braille renders 2x4 pixels per character
+func (s screen) braille() []string {
+	lines := make([]string, 0, (s.height()+3)/4)
+	for y := 0; y < s.height(); y += 4 {
+		var line strings.Builder
+		for x := 0; x < s.width(); x += 2 {
+			ch := rune(0x2800)
+			for dx := 0; dx < 2; dx++ {
+				for dy := 0; dy < 4; dy++ {
+					if s.pixel(x+dx, y+dy) {
+						ch |= brailleDots[dx][dy]
+					}
+				}
+			}
+			line.WriteRune(ch)
+		}
+		lines = append(lines, line.String())
+	}
+	return lines
+}
+
+// halfBlock renders 1x2 pixels per character
+func (s screen) halfBlock() []string {
+	lines := make([]string, 0, (s.height()+1)/2)
+	for y := 0; y < s.height(); y += 2 {
+		var line strings.Builder
+		for x := 0; x < s.width(); x++ {
+			upper, lower := s.pixel(x, y), s.pixel(x, y+1)
+			switch {
+			case upper && lower:
+				line.WriteRune('█')
+			case upper:
+				line.WriteRune('▀')
+			case lower:
+				line.WriteRune('▄')
+			default:
+				line.WriteRune(' ')
+			}
+		}
+		lines = append(lines, line.String())
+	}
+	return lines
+}
+
+// render returns the lines of the screen in the mode braille or half
+func render(m *cpu.Machine, mode string, scale int) ([]string, error) {
+	s := screen{m: m, scale: scale}
+	switch mode {
+	case "braille":
+		return s.braille(), nil
+	case "half":
+		return s.halfBlock(), nil
+	}
+	return nil, fmt.Errorf("invalid mode %s. expect braille or half", mode)
+}
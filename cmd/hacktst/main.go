@@ -0,0 +1,40 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/wongak/nand2tetris/pkg/hack/tst"
+)
+
+var (
+	verbose bool
+)
+
+func main() {
+	flag.BoolVar(&verbose, "v", false, "verbose")
+	flag.Parse()
+
+	if len(flag.Args()) == 0 {
+		fmt.Println("expecting at least one argument. tst file(s) to run")
+		os.Exit(1)
+	}
+
+	failed := false
+	for _, scriptFileName := range flag.Args() {
+		if verbose {
+			fmt.Printf("running %s...\n", scriptFileName)
+		}
+		err := tst.RunFile(scriptFileName)
+		if err != nil {
+			fmt.Println(err)
+			failed = true
+			continue
+		}
+		fmt.Printf("%s: End of script - Comparison ended successfully\n", scriptFileName)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
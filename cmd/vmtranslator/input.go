@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/wongak/nand2tetris/pkg/hack/vm/translator"
+)
+
+// resolveInputs returns the .vm files to translate in translation order
+// and the name of the output file.
+//
+// The files are collected by translator.Inputs. If outFileName is
+// empty, the output is written next to the input: a single directory dir
+// is translated to dir/dir.asm, a single file Foo.vm to Foo.asm. Multiple
+// arguments require an explicit output file name.
+func resolveInputs(args []string, outFileName string) ([]string, string, error) {
+	fileNames, err := translator.Inputs(args)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if outFileName != "" {
+		return fileNames, outFileName, nil
+	}
+	if len(args) > 1 {
+		return nil, "", fmt.Errorf("expecting output file -o for multiple arguments")
+	}
+	if len(fileNames) == 1 && filepath.Clean(args[0]) == fileNames[0] {
+		fileName := fileNames[0]
+		return fileNames, strings.TrimSuffix(fileName, filepath.Ext(fileName)) + ".asm", nil
+	}
+	abs, err := filepath.Abs(args[0])
+	if err != nil {
+		return nil, "", fmt.Errorf("error resolving out path: %v", err)
+	}
+	return fileNames, filepath.Join(args[0], filepath.Base(abs)+".asm"), nil
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeFiles(t *testing.T, fileNames ...string) {
+	for _, fileName := range fileNames {
+		err := os.MkdirAll(filepath.Dir(fileName), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(fileName, []byte("add\n"), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// chdir changes into dir for the test
+func chdir(t *testing.T, dir string) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Chdir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		err := os.Chdir(wd)
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestResolveInputs(t *testing.T) {
+	chdir(t, t.TempDir())
+	writeFiles(t,
+		"projects/08/FibonacciElement/Main.vm",
+		"projects/08/FibonacciElement/Sys.vm",
+		"projects/08/FibonacciElement/README.txt",
+		"projects/08/SimpleFunction/SimpleFunction.vm",
+		"lib/Math.vm",
+	)
+
+	for _, c := range []struct {
+		args   []string
+		out    string
+		files  []string
+		expect string
+	}{
+		{
+			args: []string{"projects/08/FibonacciElement"},
+			files: []string{
+				"projects/08/FibonacciElement/Sys.vm",
+				"projects/08/FibonacciElement/Main.vm",
+			},
+			expect: "projects/08/FibonacciElement/FibonacciElement.asm",
+		},
+		{
+			args:   []string{"projects/08/FibonacciElement/"},
+			files:  []string{"projects/08/FibonacciElement/Sys.vm", "projects/08/FibonacciElement/Main.vm"},
+			expect: "projects/08/FibonacciElement/FibonacciElement.asm",
+		},
+		{
+			args:   []string{"lib"},
+			out:    "all.asm",
+			files:  []string{"lib/Math.vm"},
+			expect: "all.asm",
+		},
+		{
+			args:   []string{"projects/08/SimpleFunction/SimpleFunction.vm"},
+			files:  []string{"projects/08/SimpleFunction/SimpleFunction.vm"},
+			expect: "projects/08/SimpleFunction/SimpleFunction.asm",
+		},
+		{
+			args:   []string{"projects/08/SimpleFunction"},
+			files:  []string{"projects/08/SimpleFunction/SimpleFunction.vm"},
+			expect: "projects/08/SimpleFunction/SimpleFunction.asm",
+		},
+		{
+			args: []string{"lib/Math.vm", "projects/08/FibonacciElement", "lib/Math.vm"},
+			out:  "out/Prog.asm",
+			files: []string{
+				"projects/08/FibonacciElement/Sys.vm",
+				"lib/Math.vm",
+				"projects/08/FibonacciElement/Main.vm",
+			},
+			expect: "out/Prog.asm",
+		},
+	} {
+		files, out, err := resolveInputs(c.args, c.out)
+		if err != nil {
+			t.Errorf("unexpected error for %v: %v", c.args, err)
+			continue
+		}
+		if !reflect.DeepEqual(files, c.files) {
+			t.Errorf("expect files %v for %v, got %v", c.files, c.args, files)
+		}
+		if out != c.expect {
+			t.Errorf("expect output %s for %v, got %s", c.expect, c.args, out)
+		}
+	}
+}
+
+func TestResolveInputsAbsDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "Prog")
+	writeFiles(t, filepath.Join(dir, "Main.vm"))
+
+	_, out, err := resolveInputs([]string{dir}, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out != filepath.Join(dir, "Prog.asm") {
+		t.Errorf("unexpected output %s", out)
+	}
+}
+
+func TestResolveInputsInvalid(t *testing.T) {
+	chdir(t, t.TempDir())
+	writeFiles(t, "a/A.vm", "b/B.vm", "c/README.txt")
+
+	for _, args := range [][]string{
+		{},
+		{"missing.vm"},
+		{"c/README.txt"},
+		{"c"},
+		{"a", "b"},
+	} {
+		_, _, err := resolveInputs(args, "")
+		if err == nil {
+			t.Errorf("expect error for %v", args)
+		}
+	}
+}
@@ -3,59 +3,93 @@ package main
 import (
 	"flag"
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
 	"strings"
 
-	"github.com/wongak/nand2tetris/pkg/hack/vm/language"
+	asm "github.com/wongak/nand2tetris/pkg/hack/assembly/language"
+	"github.com/wongak/nand2tetris/pkg/hack/assembly/peephole"
+	"github.com/wongak/nand2tetris/pkg/hack/vm/translator"
 )
 
 var (
 	headless bool
 	verbose  bool
+	optimize bool
+	optVM    bool
+	check    bool
+	shared   bool
+	hack     bool
+	compact  bool
+	symbols  bool
+	srcMap   bool
+	output   string
 )
 
 func main() {
 	flag.BoolVar(&headless, "hl", false, "headless mode")
 	flag.BoolVar(&verbose, "v", false, "verbose")
+	flag.BoolVar(&optimize, "peephole", false, "optimize the generated assembly")
+	flag.BoolVar(&check, "check", true, "check labels, function calls and argument counts before translation. calls to undefined functions, e.g. of the OS, are warnings")
+	flag.BoolVar(&optVM, "O", false, "optimize the VM code before translation")
+	flag.BoolVar(&shared, "shared", false, "use shared call, return and comparison routines to reduce code size")
+	flag.BoolVar(&hack, "hack", false, "assemble the program and write machine code instead of assembly")
+	flag.BoolVar(&compact, "compact", false, "number static variables of a file in order of first use instead of File.i")
+	flag.BoolVar(&symbols, "sym", false, "write the symbol map of the static variables next to the output file")
+	flag.BoolVar(&srcMap, "sourcemap", false, "write the JSON source map of ROM addresses and assembly lines to VM commands next to the output file")
+	flag.StringVar(&output, "o", "", "output file. defaults to dir/dir.asm or File.asm next to the input")
 	flag.Parse()
 
-	if len(flag.Args()) != 1 {
-		fmt.Println("expecting exactly one argument. vm file to translate")
+	// the peephole optimizer rewrites the assembly across commands and
+	// may remove or reorder the first use of static variables
+	if srcMap && optimize {
+		fmt.Println("-sourcemap cannot be combined with -peephole")
+		os.Exit(1)
+	}
+	if symbols && optimize {
+		fmt.Println("-sym cannot be combined with -peephole")
 		os.Exit(1)
 	}
 
-	inputFileName := flag.Args()[0]
-
-	info, err := os.Stat(inputFileName)
+	fileNames, outFileName, err := resolveInputs(flag.Args(), output)
 	if err != nil {
-		fmt.Printf("error on stat input: %v\n", err)
+		fmt.Println(err)
 		os.Exit(1)
 	}
+	if hack && output == "" {
+		outFileName = strings.TrimSuffix(outFileName, filepath.Ext(outFileName)) + ".hack"
+	}
 
-	table := language.NewSymbolTable()
+	tr := translator.New()
+	if verbose {
+		tr.Log = os.Stdout
+	}
+	if shared {
+		tr.UseSharedRoutines()
+	}
+	if compact {
+		tr.UseCompactStatics()
+	}
 
-	var outFileName string
-	if info.IsDir() {
-		abs, err := filepath.Abs(info.Name())
-		if err != nil {
-			fmt.Printf("error resolving out path: %v", err)
-			os.Exit(1)
-		}
+	err = tr.Build(fileNames, translator.Options{
+		Headless: headless,
+		Check:    check,
+		Warn:     os.Stdout,
+		Optimize: optVM,
+	})
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
 
-		outFileName = filepath.Join(abs, filepath.Base(abs)+".asm")
-	} else {
-		fileDir := filepath.Dir(inputFileName)
-		fileBase := filepath.Base(inputFileName)
-		parts := strings.Split(fileBase, ".")
-		parts[len(parts)-1] = "asm"
-		outFileName = filepath.Join(fileDir, strings.Join(parts, "."))
+	program := tr.Program()
+	if optimize {
+		program = optimizeProgram(program)
 	}
+
 	if verbose {
 		fmt.Printf("writing %s\n", outFileName)
 	}
-
 	out, err := os.OpenFile(outFileName, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
 	if err != nil {
 		fmt.Printf("error opening output file: %v\n", err)
@@ -63,112 +97,78 @@ func main() {
 	}
 	defer out.Close()
 
-	if !headless {
-		_, err = out.WriteString(`// BOOT
-@256
-D=A
-@SP
-M=D // SP = 256
-`)
-		if err != nil {
-			fmt.Printf("error writing bootstrap: %v\n", err)
-			os.Exit(1)
-		}
-		sysinit := language.NewCall("Sys.init", 0)
-		err = sysinit.Translate(table, out)
-		if err != nil {
-			fmt.Printf("error writing bootstrap: %v\n", err)
-			os.Exit(1)
-		}
-
+	if hack {
+		err = asm.Assemble(asm.NewSymbolTable(), program, out)
+	} else {
+		err = asm.WriteCode(out, program)
+	}
+	if err != nil {
+		fmt.Printf("error writing output file: %v\n", err)
+		os.Exit(1)
 	}
 
-	if info.IsDir() {
-		dir, err := os.Open(inputFileName)
+	if symbols {
+		err = writeSymbols(tr, strings.TrimSuffix(outFileName, filepath.Ext(outFileName))+".sym")
 		if err != nil {
-			fmt.Printf("error opening dir: %v\n", err)
+			fmt.Println(err)
 			os.Exit(1)
 		}
-		defer dir.Close()
-		content, err := dir.Readdir(-1)
-		if err != nil {
-			fmt.Printf("error reading dir: %v\n", err)
-			os.Exit(1)
-		}
-		for _, f := range content {
-			if f.Name() == "." || f.Name() == ".." {
-				continue
-			}
-			parts := strings.Split(f.Name(), ".")
-			if parts[len(parts)-1] != "vm" {
-				continue
-			}
-			if verbose {
-				fmt.Print(f.Name(), " ")
-			}
-			err = parseFile(out, table, filepath.Join(dir.Name(), f.Name()))
-			if err != nil {
-				fmt.Printf("parse error on file %s: %v\n", f.Name(), err)
-				os.Exit(1)
-			}
-			if verbose {
-				fmt.Println(".")
-			}
-		}
-	} else {
-		err = parseFile(out, table, inputFileName)
-		if err != nil {
-			fmt.Printf("parse error on file %s: %v\n", inputFileName, err)
-			os.Exit(1)
-		}
-
 	}
 
-	if headless {
-		_, err = out.WriteString(`// END
-(END)
-@END
-0;JMP
-`)
+	if srcMap {
+		err = writeSourceMap(tr, strings.TrimSuffix(outFileName, filepath.Ext(outFileName))+".map.json")
 		if err != nil {
-			fmt.Printf("error writing halt: %v\n", err)
+			fmt.Println(err)
 			os.Exit(1)
 		}
 	}
 }
 
-func parseFile(wr io.Writer, table *language.SymbolTable, fileName string) error {
-	fileBase := filepath.Base(fileName)
-	parts := strings.Split(fileBase, ".")
-	cfgName := parts[:len(parts)-1]
-	symbolTableFileName := strings.Join(cfgName, ".")
-
-	in, err := os.Open(fileName)
+// writeSymbols writes the symbol map of the translated program
+func writeSymbols(tr *translator.Translator, fileName string) error {
+	if verbose {
+		fmt.Printf("writing %s\n", fileName)
+	}
+	out, err := os.OpenFile(fileName, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("error opening symbol file: %v", err)
+	}
+	defer out.Close()
+	err = tr.WriteSymbols(out)
 	if err != nil {
-		return fmt.Errorf("error opening vm file: %v", err)
+		return fmt.Errorf("error writing symbol file: %v", err)
 	}
-	defer in.Close()
+	return nil
+}
 
+// writeSourceMap writes the source map of the translated program
+func writeSourceMap(tr *translator.Translator, fileName string) error {
 	if verbose {
-		fmt.Printf("parsing %s...\n", symbolTableFileName)
+		fmt.Printf("writing %s\n", fileName)
 	}
-	p := language.NewParser(in)
-
-	err = p.Run(table, symbolTableFileName)
+	out, err := os.OpenFile(fileName, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("error opening source map file: %v", err)
+	}
+	defer out.Close()
+	err = tr.WriteSourceMap(out)
 	if err != nil {
-		return err
+		return fmt.Errorf("error writing source map file: %v", err)
 	}
+	return nil
+}
 
-	for _, cmd := range p.Tree() {
-		if verbose {
-			fmt.Printf("  %+v\n", cmd)
-		}
-		err = cmd.Translate(table, wr)
-		if err != nil {
-			return err
-		}
+// optimizeProgram runs the peephole optimizer on the assembly and reports
+// the number of instructions saved
+func optimizeProgram(cmds []asm.Command) []asm.Command {
+	optimized := peephole.Optimize(cmds)
 
+	before, after := peephole.Count(cmds), peephole.Count(optimized)
+	saved := before - after
+	var percent float64
+	if before > 0 {
+		percent = float64(saved) * 100 / float64(before)
 	}
-
-	return nil
+	fmt.Printf("peephole: %d instructions, %d saved (%.1f%%)\n", after, saved, percent)
+	return optimized
 }
@@ -0,0 +1,110 @@
+// Package disassembler translates Hack machine code back to assembly.
+//
+// Addresses loaded right before a jump become labels L<address> at
+// their ROM address. Addresses of the predefined registers used for
+// memory access and the SCREEN and KBD addresses are written as their
+// symbols. The resulting assembly reassembles to the same machine code.
+package disassembler
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/wongak/nand2tetris/pkg/hack/assembly/language"
+)
+
+// registers holds the symbols of the predefined registers by address
+var registers = []string{
+	"SP", "LCL", "ARG", "THIS", "THAT",
+	"R5", "R6", "R7", "R8", "R9", "R10", "R11", "R12", "R13", "R14", "R15",
+}
+
+const (
+	screen   = 0x4000
+	keyboard = 0x6000
+)
+
+// Read reads machine code of 16 binary digits per line. Empty lines
+// are skipped.
+func Read(r io.Reader) ([]uint16, error) {
+	words := make([]uint16, 0)
+	sc := bufio.NewScanner(r)
+	line := 0
+	for sc.Scan() {
+		line++
+		text := strings.TrimSpace(sc.Text())
+		if text == "" {
+			continue
+		}
+		word, err := strconv.ParseUint(text, 2, 16)
+		if err != nil || len(text) != 16 {
+			return nil, fmt.Errorf("invalid instruction %q on line %d. expect 16 binary digits", text, line)
+		}
+		words = append(words, uint16(word))
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return words, nil
+}
+
+// Label returns the synthesized label of the ROM address
+func Label(addr int) string {
+	return fmt.Sprintf("L%d", addr)
+}
+
+// Disassemble decodes the machine code into assembly commands
+func Disassemble(words []uint16) ([]language.Command, error) {
+	cmds := make([]language.Command, len(words))
+	for i, word := range words {
+		cmd, err := language.Decode(word)
+		if err != nil {
+			return nil, fmt.Errorf("instruction %d: %v", i, err)
+		}
+		cmds[i] = cmd
+	}
+
+	targets := make(map[int]bool)
+	for i, cmd := range cmds {
+		a, ok := cmd.(*language.AInstruction)
+		if !ok {
+			continue
+		}
+		addr, _ := strconv.Atoi(a.Address)
+		var next *language.CInstruction
+		if i+1 < len(cmds) {
+			next, _ = cmds[i+1].(*language.CInstruction)
+		}
+		switch {
+		case next != nil && next.Jump != language.NULL && addr <= len(cmds):
+			targets[addr] = true
+			a.Address = Label(addr)
+		case addr == screen:
+			a.Address = "SCREEN"
+		case addr == keyboard:
+			a.Address = "KBD"
+		case next != nil && addr < len(registers) && accessesMemory(next):
+			a.Address = registers[addr]
+		}
+	}
+
+	tree := make([]language.Command, 0, len(cmds)+len(targets))
+	for i, cmd := range cmds {
+		if targets[i] {
+			tree = append(tree, &language.Label{Name: Label(i)})
+		}
+		tree = append(tree, cmd)
+	}
+	if targets[len(cmds)] {
+		tree = append(tree, &language.Label{Name: Label(len(cmds))})
+	}
+	return tree, nil
+}
+
+// accessesMemory returns true if the instruction reads or writes M
+func accessesMemory(c *language.CInstruction) bool {
+	return strings.Contains(c.Comp, "M") || strings.Contains(c.Dest, "M")
+}
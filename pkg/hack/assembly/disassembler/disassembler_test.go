@@ -0,0 +1,117 @@
+package disassembler_test
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/wongak/nand2tetris/pkg/hack/assembly/disassembler"
+	"github.com/wongak/nand2tetris/pkg/hack/assembly/language"
+)
+
+func assemble(t *testing.T, src string) []byte {
+	p := language.NewParser(strings.NewReader(src))
+	err := p.Run()
+	if err != nil {
+		t.Fatalf("error on parse: %v", err)
+	}
+	buf := bytes.NewBuffer(nil)
+	err = language.Assemble(language.NewSymbolTable(), p.Tree(), buf)
+	if err != nil {
+		t.Fatalf("error on assemble: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func disassemble(t *testing.T, hack []byte) string {
+	words, err := disassembler.Read(bytes.NewReader(hack))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmds, err := disassembler.Disassemble(words)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := bytes.NewBuffer(nil)
+	err = language.WriteCode(buf, cmds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestDisassemble(t *testing.T) {
+	hack := assemble(t, `
+	@SP
+	M=M+1
+	@0
+	D=A
+	@16
+	M=D
+(LOOP)
+	@SCREEN
+	D=A
+	@KBD
+	D=M
+	@LOOP
+	D;JEQ
+	@R13
+	M=D
+	@END
+	0;JMP
+	@5
+	D=A
+(END)
+`)
+	expect := `	@SP
+	M=M+1
+	@0
+	D=A
+	@16
+	M=D
+(L6)
+	@SCREEN
+	D=A
+	@KBD
+	D=M
+	@L6
+	D;JEQ
+	@R13
+	M=D
+	@L18
+	0;JMP
+	@5
+	D=A
+(L18)
+`
+	got := disassemble(t, hack)
+	if got != expect {
+		t.Errorf("expect\n%s\ngot\n%s", expect, got)
+	}
+}
+
+func TestReassemble(t *testing.T) {
+	for _, fileName := range []string{"../../../../mult.asm", "../../../../constmult.asm", "../../../../fill.asm"} {
+		src, err := os.ReadFile(fileName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hack := assemble(t, string(src))
+		again := assemble(t, disassemble(t, hack))
+		if !bytes.Equal(hack, again) {
+			t.Errorf("%s: expect identical machine code after disassembly", fileName)
+		}
+	}
+}
+
+func TestDisassembleInvalid(t *testing.T) {
+	_, err := disassembler.Read(strings.NewReader("0000000000000001\n000000000000002\n"))
+	if err == nil {
+		t.Error("expect error on invalid digits")
+	}
+	_, err = disassembler.Disassemble([]uint16{0, 0xe040})
+	if err == nil || !strings.Contains(err.Error(), "instruction 1") {
+		t.Errorf("expect error on undefined comp bits, got %v", err)
+	}
+}
@@ -4,9 +4,14 @@ import (
 	"fmt"
 	"io"
 	"strconv"
+	"strings"
 )
 
+// MaxAddress is the largest value of an A-instruction, 15 bits
+const MaxAddress = 0x7fff
+
 type AInstruction struct {
+	node
 	Address string
 }
 
@@ -17,6 +22,9 @@ func (a *AInstruction) String() string {
 func (a *AInstruction) Translate(t *SymbolTable, wr io.Writer) error {
 	var address int
 	if ai, err := strconv.ParseInt(a.Address, 10, 64); err == nil {
+		if ai < 0 || ai > MaxAddress {
+			return fmt.Errorf("address %d out of range 0..%d", ai, MaxAddress)
+		}
 		address = int(ai)
 	} else {
 		address = t.Label(a.Address)
@@ -43,7 +51,11 @@ func parseAInstruction(p *Parser, ctx ParserContext) (ParserContext, stateFunc)
 		panic("internal error")
 	}
 
-	tok, lit, err = p.scanIgnore()
+	// the address must be on the same line
+	tok, lit, err = p.scan()
+	if err == nil && tok == WS && !strings.ContainsRune(lit, '\n') {
+		tok, lit, err = p.scan()
+	}
 	if err != nil {
 		return ctx, parseError(err)
 	}
@@ -51,6 +63,7 @@ func parseAInstruction(p *Parser, ctx ParserContext) (ParserContext, stateFunc)
 		return ctx, parseError(fmt.Errorf("invalid token %s (%s) for A-Instruction. Expect VALUE.", tok, lit))
 	}
 	a := &AInstruction{
+		node:    node{pos: ctx.pos},
 		Address: lit,
 	}
 	return ctx, command(a)
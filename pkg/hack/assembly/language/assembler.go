@@ -0,0 +1,66 @@
+package language
+
+import (
+	"fmt"
+	"io"
+)
+
+// ResolveLabels is the first pass over the parse tree. It registers every
+// label on the ROM address of the instruction following it, so that the
+// second pass does not allocate forward references as variables.
+func ResolveLabels(t *SymbolTable, tree []Command) error {
+	start := t.instruction
+	for _, cmd := range tree {
+		l, ok := cmd.(*Label)
+		if !ok {
+			if _, ok := cmd.(*Comment); !ok {
+				t.RegisterInstruction()
+			}
+			continue
+		}
+		if _, ok := t.labels[l.Name]; ok {
+			return &Error{Pos: l.Pos(), Err: fmt.Errorf("label %s already declared", l.Name)}
+		}
+		t.RegisterLabel(l.Name)
+	}
+	t.instruction = start
+	return nil
+}
+
+// Assemble translates the parse tree to machine code in two passes.
+// The first pass resolves the labels, the second pass translates the
+// instructions and allocates the variables from address 16 upward.
+func Assemble(t *SymbolTable, tree []Command, wr io.Writer) error {
+	err := ResolveLabels(t, tree)
+	if err != nil {
+		return err
+	}
+	for _, cmd := range tree {
+		err = cmd.Translate(t, wr)
+		if err != nil {
+			return &Error{Pos: cmd.Pos(), Err: fmt.Errorf("error translating %s: %v", cmd, err)}
+		}
+	}
+	return nil
+}
+
+// WriteCode writes the commands as assembly code, one command per line.
+// Instructions are indented, labels and comments are not.
+func WriteCode(wr io.Writer, cmds []Command) error {
+	for _, cmd := range cmds {
+		indent, comment := "\t", ""
+		switch c := cmd.(type) {
+		case *Label, *Comment:
+			indent = ""
+		case *CInstruction:
+			if c.Comment != "" {
+				comment = " // " + c.Comment
+			}
+		}
+		_, err := fmt.Fprintf(wr, "%s%s%s\n", indent, cmd, comment)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
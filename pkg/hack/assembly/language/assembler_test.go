@@ -0,0 +1,145 @@
+package language_test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	. "github.com/wongak/nand2tetris/pkg/hack/assembly/language"
+)
+
+func TestAssembleForwardReference(t *testing.T) {
+	input := strings.NewReader(`
+	@R0
+	D=M
+	@i
+	M=D // i = R0
+(LOOP)
+	@i
+	D=M
+	@END
+	D;JEQ // forward reference
+	@i
+	M=M-1
+	@LOOP
+	0;JMP
+(END)
+	@END
+	0;JMP
+	`)
+	p := NewParser(input)
+	err := p.Run()
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	buf := bytes.NewBuffer(nil)
+	tbl := NewSymbolTable()
+	err = Assemble(tbl, p.Tree(), buf)
+	if err != nil {
+		t.Errorf("unexpected assemble error: %v", err)
+		return
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 14 {
+		t.Errorf("expect 14 instructions, got %d", len(lines))
+		return
+	}
+	expect := map[int]string{
+		2:  "0" + fmt.Sprintf("%015b", 16),
+		4:  "0" + fmt.Sprintf("%015b", 16),
+		6:  "0" + fmt.Sprintf("%015b", 12),
+		10: "0" + fmt.Sprintf("%015b", 4),
+		12: "0" + fmt.Sprintf("%015b", 12),
+	}
+	for i, e := range expect {
+		if lines[i] != e {
+			t.Errorf("unexpected instruction %d. expect\n%s, got\n%s", i, e, lines[i])
+		}
+	}
+	if tbl.CurrentInstruction() != 14 {
+		t.Errorf("expect current instruction 14, got %d", tbl.CurrentInstruction())
+	}
+}
+
+func TestAssembleDuplicateLabel(t *testing.T) {
+	p := NewParser(strings.NewReader(`
+(A)
+	@A
+(A)
+	0;JMP
+	`))
+	err := p.Run()
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	err = Assemble(NewSymbolTable(), p.Tree(), bytes.NewBuffer(nil))
+	if err == nil {
+		t.Error("expect error on duplicate label")
+	}
+}
+
+func TestAssembleComments(t *testing.T) {
+	tree := []Command{
+		&Comment{Text: "loop"},
+		&Label{Name: "LOOP"},
+		&AInstruction{Address: "LOOP"},
+		&Comment{Text: "jump"},
+		&CInstruction{Comp: "0", Jump: JMP, Comment: "goto LOOP"},
+		&Label{Name: "END"},
+		&AInstruction{Address: "END"},
+	}
+	buf := bytes.NewBuffer(nil)
+	err := Assemble(NewSymbolTable(), tree, buf)
+	if err != nil {
+		t.Errorf("unexpected assemble error: %v", err)
+		return
+	}
+	expect := "0000000000000000\n1110101010000111\n0000000000000010\n"
+	if buf.String() != expect {
+		t.Errorf("unexpected machine code. expect\n%s, got\n%s", expect, buf.String())
+	}
+
+	buf.Reset()
+	err = WriteCode(buf, tree)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	expect = "// loop\n(LOOP)\n\t@LOOP\n// jump\n\t0;JMP // goto LOOP\n(END)\n\t@END\n"
+	if buf.String() != expect {
+		t.Errorf("unexpected code. expect\n%s, got\n%s", expect, buf.String())
+	}
+}
+
+func TestAssembleAddressRange(t *testing.T) {
+	for _, c := range []struct {
+		address string
+		err     string
+	}{
+		{"32767", ""},
+		{"0", ""},
+		{"-1", "2:2: error translating @-1: address -1 out of range 0..32767"},
+		{"40000", "2:2: error translating @40000: address 40000 out of range 0..32767"},
+	} {
+		p := NewParser(strings.NewReader("\n\t@" + c.address + "\n"))
+		err := p.Run()
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", c.address, err)
+		}
+		buf := bytes.NewBuffer(nil)
+		err = Assemble(NewSymbolTable(), p.Tree(), buf)
+		if c.err == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", c.address, err)
+			}
+			continue
+		}
+		if err == nil || err.Error() != c.err {
+			t.Errorf("%s: expect error %s, got %v", c.address, c.err, err)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package language
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// compBits maps the comp mnemonic to the a-bit and the six c-bits
+var compBits = map[string]string{
+	"0":   "0101010",
+	"1":   "0111111",
+	"-1":  "0111010",
+	"D":   "0001100",
+	"A":   "0110000",
+	"!D":  "0001101",
+	"!A":  "0110001",
+	"-D":  "0001111",
+	"-A":  "0110011",
+	"D+1": "0011111",
+	"A+1": "0110111",
+	"D-1": "0001110",
+	"A-1": "0110010",
+	"D+A": "0000010",
+	"D-A": "0010011",
+	"A-D": "0000111",
+	"D&A": "0000000",
+	"D|A": "0010101",
+
+	"M":   "1110000",
+	"!M":  "1110001",
+	"-M":  "1110011",
+	"M+1": "1110111",
+	"M-1": "1110010",
+	"D+M": "1000010",
+	"D-M": "1010011",
+	"M-D": "1000111",
+	"D&M": "1000000",
+	"D|M": "1010101",
+}
+
+// compMnemonics maps the a-bit and the six c-bits to the comp mnemonic
+var compMnemonics = make(map[string]string)
+
+func init() {
+	for comp, bits := range compBits {
+		compMnemonics[bits] = comp
+	}
+}
+
+var jumpBits = map[Token]string{
+	NULL: "000",
+	JGT:  "001",
+	JEQ:  "010",
+	JGE:  "011",
+	JLT:  "100",
+	JNE:  "101",
+	JLE:  "110",
+	JMP:  "111",
+}
+
+func isJump(tok Token) bool {
+	_, ok := jumpBits[tok]
+	return ok && tok != NULL
+}
+
+// CInstruction represents a compute instruction dest=comp;jump
+//
+// Dest and Jump are optional. An empty Jump is represented by the NULL token.
+type CInstruction struct {
+	node
+	Dest string
+	Comp string
+	Jump Token
+	// Comment is a trailing comment written by WriteCode. The parser
+	// drops comments.
+	Comment string
+}
+
+func (c *CInstruction) String() string {
+	var str string
+	if c.Dest != "" {
+		str = c.Dest + "="
+	}
+	str += c.Comp
+	if c.Jump != NULL {
+		str += ";" + c.Jump.String()
+	}
+	return str
+}
+
+// Translate writes the instruction as 111a cccc ccdd djjj
+func (c *CInstruction) Translate(t *SymbolTable, wr io.Writer) error {
+	comp, ok := compBits[c.Comp]
+	if !ok {
+		return fmt.Errorf("invalid comp %s", c.Comp)
+	}
+	dest, err := destBits(c.Dest)
+	if err != nil {
+		return err
+	}
+	jump, ok := jumpBits[c.Jump]
+	if !ok {
+		return fmt.Errorf("invalid jump %s", c.Jump)
+	}
+	_, err = fmt.Fprintf(wr, "111%s%s%s\n", comp, dest, jump)
+	if err != nil {
+		return err
+	}
+
+	t.RegisterInstruction()
+	return nil
+}
+
+// destBits returns the d-bits for the dest registers in any order
+func destBits(dest string) (string, error) {
+	bits := []byte("000")
+	for _, r := range dest {
+		var i int
+		switch r {
+		case 'A':
+			i = 0
+		case 'D':
+			i = 1
+		case 'M':
+			i = 2
+		default:
+			return "", fmt.Errorf("invalid dest %s", dest)
+		}
+		if bits[i] == '1' {
+			return "", fmt.Errorf("invalid dest %s: duplicate register %c", dest, r)
+		}
+		bits[i] = '1'
+	}
+	return string(bits), nil
+}
+
+func parseCInstruction(p *Parser, ctx ParserContext) (ParserContext, stateFunc) {
+	c := &CInstruction{
+		node: node{pos: ctx.pos},
+		Jump: NULL,
+	}
+	var buf strings.Builder
+	compPos := ctx.pos
+	for {
+		// a C-instruction may not contain whitespace, so scan without ignoring
+		tok, lit, err := p.scan()
+		if err != nil {
+			return ctx, parseError(err)
+		}
+		switch tok {
+		case VALUE, OPERATOR:
+			buf.WriteString(lit)
+			continue
+		case EQUALS:
+			if c.Dest != "" || buf.Len() == 0 {
+				return ctx, parseError(fmt.Errorf("invalid token %s (%s) for C-Instruction", tok, lit))
+			}
+			if _, err := destBits(buf.String()); err != nil {
+				return ctx, parseError(err)
+			}
+			c.Dest = buf.String()
+			buf.Reset()
+			compPos = p.s.Pos()
+			compPos.File = p.file
+			continue
+		case SEMICOLON:
+			tok, lit, err = p.scan()
+			if err != nil {
+				return ctx, parseError(err)
+			}
+			if !isJump(tok) {
+				return ctx, parseError(fmt.Errorf("invalid token %s (%s) for C-Instruction. Expect jump.", tok, lit))
+			}
+			c.Jump = tok
+		default:
+			p.unscan()
+		}
+		break
+	}
+	c.Comp = buf.String()
+	if _, ok := compBits[c.Comp]; !ok {
+		return ctx, parseError(&Error{
+			Pos: compPos,
+			Err: fmt.Errorf("invalid comp %q for C-Instruction", c.Comp),
+		})
+	}
+	return ctx, command(c)
+}
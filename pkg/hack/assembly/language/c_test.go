@@ -0,0 +1,207 @@
+package language_test
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+
+	. "github.com/wongak/nand2tetris/pkg/hack/assembly/language"
+)
+
+func TestParseCInstruction(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	input := strings.NewReader(`
+	D=M
+	M=D // comment
+	M=0
+	D=D+M
+	M=D-1
+	AM=M-1
+	M=-1
+	D=!M
+	D=D|M
+	D=D&A
+	D;JEQ
+	0;JMP
+	AMD=M+1;JNE
+	`)
+	p := NewParser(input)
+	err := p.Run()
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	cmds := p.Tree()
+	tbl := NewSymbolTable()
+	for _, cmd := range cmds {
+		t.Logf("cmd: %s", cmd)
+		err = cmd.Translate(tbl, buf)
+		if err != nil {
+			t.Errorf("unexpected translate error: %v", err)
+			return
+		}
+	}
+	expect := []string{
+		"1111110000010000",
+		"1110001100001000",
+		"1110101010001000",
+		"1111000010010000",
+		"1110001110001000",
+		"1111110010101000",
+		"1110111010001000",
+		"1111110001010000",
+		"1111010101010000",
+		"1110000000010000",
+		"1110001100000010",
+		"1110101010000111",
+		"1111110111111101",
+	}
+	sc := bufio.NewScanner(strings.NewReader(buf.String()))
+	for i, e := range expect {
+		if !sc.Scan() {
+			t.Errorf("expect scan %d to succeed", i)
+			return
+		}
+		if sc.Text() != e {
+			t.Errorf("unexpected translation of %s. expect\n%s, got\n%s", cmds[i], e, sc.Text())
+		}
+	}
+	if tbl.CurrentInstruction() != len(expect) {
+		t.Errorf("expect current instruction %d, got %d", len(expect), tbl.CurrentInstruction())
+	}
+}
+
+func TestParseCInstructionInvalid(t *testing.T) {
+	for _, input := range []string{
+		"D=X",
+		"Q=D",
+		"DD=1",
+		"M=",
+		"D;",
+		"D;D",
+		// only the mnemonics of the spec
+		"D=M+D",
+		"M=1+D",
+	} {
+		p := NewParser(strings.NewReader(input))
+		err := p.Run()
+		if err == nil {
+			t.Errorf("expect error for %s", input)
+		}
+	}
+}
+
+func TestParseErrorLine(t *testing.T) {
+	p := NewParser(strings.NewReader(`// comment
+	@1
+	D=M
+
+	M=X
+	`))
+	err := p.Run()
+	if err == nil {
+		t.Error("expect error")
+		return
+	}
+	errs, ok := err.(ErrorList)
+	if !ok || len(errs) != 1 {
+		t.Errorf("expect one parse error, got %T %v", err, err)
+		return
+	}
+	perr := errs[0]
+	if perr.Line != 5 || perr.Col != 4 {
+		t.Errorf("expect error on 5:4, got %s", perr.Pos)
+	}
+}
+
+func TestParseErrorPos(t *testing.T) {
+	p := NewFileParser(strings.NewReader("@1\n  D=M\n  M=D;JXX\n"), "Prog.asm")
+	err := p.Run()
+	if err == nil {
+		t.Error("expect error")
+		return
+	}
+	if err.Error() != "Prog.asm:3:7: invalid token VALUE (JXX) for C-Instruction. Expect jump." {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	p = NewParser(strings.NewReader("@1\n(LOOP)\n@LOOP\n(LOOP)\n"))
+	err = p.Run()
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	for i, cmd := range p.Tree() {
+		if cmd.Pos().Line != i+1 || cmd.Pos().Col != 1 {
+			t.Errorf("unexpected position %s of %s", cmd.Pos(), cmd)
+		}
+	}
+	err = Assemble(NewSymbolTable(), p.Tree(), bytes.NewBuffer(nil))
+	if err == nil || err.Error() != "4:1: label LOOP already declared" {
+		t.Errorf("expect duplicate label error on 4:1, got %v", err)
+	}
+}
+
+func TestParseErrorRecovery(t *testing.T) {
+	p := NewFileParser(strings.NewReader(`@1
+D=X // invalid comp
+D;
+@
+M=D
+(1)
+0;JMP
+`), "Prog.asm")
+	err := p.Run()
+	errs, ok := err.(ErrorList)
+	if !ok {
+		t.Errorf("expect error list, got %T %v", err, err)
+		return
+	}
+	expect := []string{
+		"Prog.asm:2:3: invalid comp \"X\" for C-Instruction",
+		"Prog.asm:3:3: invalid token WS (\n) for C-Instruction. Expect jump.",
+		"Prog.asm:4:2: invalid token WS (\n) for A-Instruction. Expect VALUE.",
+		"Prog.asm:6:2: invalid label 1. Labels may not be numeric.",
+	}
+	if len(errs) != len(expect) {
+		t.Errorf("expect %d errors, got %d:\n%v", len(expect), len(errs), err)
+		return
+	}
+	for i, e := range expect {
+		if errs[i].Error() != e {
+			t.Errorf("expect error %q, got %q", e, errs[i].Error())
+		}
+	}
+	// valid commands are parsed
+	if len(p.Tree()) != 3 {
+		t.Errorf("expect 3 commands, got %v", p.Tree())
+	}
+}
+
+func TestParseErrorRecoveryScanner(t *testing.T) {
+	// the scanner errors while skipping the first line include the line
+	// break
+	p := NewFileParser(strings.NewReader("D=X / /\n@1\nM=D / x\n0;JMP\n"), "Prog.asm")
+	err := p.Run()
+	errs, ok := err.(ErrorList)
+	if !ok {
+		t.Fatalf("expect error list, got %T %v", err, err)
+	}
+	expect := []string{
+		"Prog.asm:1:3: invalid comp \"X\" for C-Instruction",
+		"Prog.asm:3:5: invalid single /",
+	}
+	if len(errs) != len(expect) {
+		t.Fatalf("expect %d errors, got %d:\n%v", len(expect), len(errs), err)
+	}
+	for i, e := range expect {
+		if errs[i].Error() != e {
+			t.Errorf("expect error %q, got %q", e, errs[i].Error())
+		}
+	}
+	if len(p.Tree()) != 3 {
+		t.Errorf("expect 3 commands, got %v", p.Tree())
+	}
+}
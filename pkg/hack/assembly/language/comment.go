@@ -0,0 +1,21 @@
+package language
+
+import (
+	"io"
+)
+
+// Comment is a comment line. The parser drops comments, they are only
+// part of generated programs.
+type Comment struct {
+	node
+	Text string
+}
+
+func (c *Comment) String() string {
+	return "// " + c.Text
+}
+
+// Translate does not produce any machine code
+func (c *Comment) Translate(t *SymbolTable, wr io.Writer) error {
+	return nil
+}
@@ -0,0 +1,38 @@
+package language
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// destMnemonics maps the d-bits to the dest registers
+var destMnemonics = []string{"", "M", "D", "MD", "A", "AM", "AD", "AMD"}
+
+// Decode decodes a word of machine code into an A- or C-instruction. The
+// address of A-instructions is numeric. C-instructions which can not be
+// written as assembly, i.e. with undefined comp bits or without the two
+// leading 1 bits, return an error.
+func Decode(word uint16) (Command, error) {
+	if word&0x8000 == 0 {
+		return &AInstruction{Address: strconv.Itoa(int(word))}, nil
+	}
+	bits := fmt.Sprintf("%016b", word)
+	if bits[:3] != "111" {
+		return nil, fmt.Errorf("invalid C-instruction %s. expect 111 prefix", bits)
+	}
+	comp, ok := compMnemonics[bits[3:10]]
+	if !ok {
+		return nil, fmt.Errorf("invalid C-instruction %s. undefined comp bits %s", bits, bits[3:10])
+	}
+	c := &CInstruction{
+		Dest: destMnemonics[(word>>3)&0x7],
+		Comp: comp,
+	}
+	for tok, jump := range jumpBits {
+		if jump == bits[13:] {
+			c.Jump = tok
+			break
+		}
+	}
+	return c, nil
+}
@@ -0,0 +1,66 @@
+package language_test
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/wongak/nand2tetris/pkg/hack/assembly/language"
+)
+
+func TestDecode(t *testing.T) {
+	for _, c := range []struct {
+		word   uint16
+		expect string
+	}{
+		{0x0000, "@0"},
+		{0x7fff, "@32767"},
+		{0xfc10, "D=M"},
+		{0xe308, "M=D"},
+		{0xfdd8, "MD=M+1"},
+		{0xea87, "0;JMP"},
+		{0xe33f, "AMD=D;JMP"},
+		{0xf4ed, "AM=D-M;JNE"},
+	} {
+		cmd, err := language.Decode(c.word)
+		if err != nil {
+			t.Errorf("%016b: unexpected error: %v", c.word, err)
+			continue
+		}
+		if cmd.String() != c.expect {
+			t.Errorf("%016b: expect %s, got %s", c.word, c.expect, cmd)
+		}
+	}
+
+	for _, word := range []uint16{0x8000, 0xa000, 0xe040} {
+		_, err := language.Decode(word)
+		if err == nil {
+			t.Errorf("%016b: expect error", word)
+		}
+	}
+}
+
+func TestDecodeRoundTrip(t *testing.T) {
+	var decoded int
+	for w := 0; w <= 0xffff; w++ {
+		word := uint16(w)
+		cmd, err := language.Decode(word)
+		if err != nil {
+			continue
+		}
+		decoded++
+		buf := bytes.NewBuffer(nil)
+		err = cmd.Translate(language.NewSymbolTable(), buf)
+		if err != nil {
+			t.Fatalf("%016b: error translating %s: %v", word, cmd, err)
+		}
+		expect := fmt.Sprintf("%016b\n", word)
+		if buf.String() != expect {
+			t.Fatalf("%s: expect %s, got %s", cmd, expect, buf.String())
+		}
+	}
+	// all A-instructions and 28 comp mnemonics with 8 dest and 8 jump bits
+	if decoded != 0x8000+28*64 {
+		t.Errorf("expect %d decoded words, got %d", 0x8000+28*64, decoded)
+	}
+}
@@ -0,0 +1,61 @@
+package language
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Label represents a label declaration (LABEL)
+type Label struct {
+	node
+	Name string
+}
+
+func (l *Label) String() string {
+	return fmt.Sprintf("(%s)", l.Name)
+}
+
+// Translate does not produce any machine code. Labels should be registered
+// by ResolveLabels before. Otherwise the label is registered on the current
+// instruction, which only works for backward references.
+func (l *Label) Translate(t *SymbolTable, wr io.Writer) error {
+	if _, ok := t.labels[l.Name]; !ok {
+		t.RegisterLabel(l.Name)
+	}
+	return nil
+}
+
+func parseLabel(p *Parser, ctx ParserContext) (ParserContext, stateFunc) {
+	tok, lit, err := p.scanIgnore()
+	if err != nil {
+		return ctx, parseError(err)
+	}
+	if tok != LABEL_START {
+		panic("internal error")
+	}
+
+	tok, lit, err = p.scan()
+	if err != nil {
+		return ctx, parseError(err)
+	}
+	if tok != VALUE {
+		return ctx, parseError(fmt.Errorf("invalid token %s (%s) for label. Expect VALUE.", tok, lit))
+	}
+	if _, err := strconv.ParseInt(lit, 10, 64); err == nil {
+		return ctx, parseError(fmt.Errorf("invalid label %s. Labels may not be numeric.", lit))
+	}
+	l := &Label{
+		node: node{pos: ctx.pos},
+		Name: lit,
+	}
+
+	tok, lit, err = p.scan()
+	if err != nil {
+		return ctx, parseError(err)
+	}
+	if tok != LABEL_END {
+		return ctx, parseError(fmt.Errorf("invalid token %s (%s) for label. Expect LABEL_END.", tok, lit))
+	}
+	return ctx, command(l)
+}
@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"strings"
 )
 
 // Token is a lexical token
@@ -22,6 +23,7 @@ const (
 
 	AT
 	EQUALS
+	OPERATOR
 
 	LABEL_START
 	LABEL_END
@@ -54,6 +56,8 @@ func (t Token) String() string {
 		return "AT"
 	case EQUALS:
 		return "EQUALS"
+	case OPERATOR:
+		return "OPERATOR"
 	case LABEL_START:
 		return "LABEL_START"
 	case LABEL_END:
@@ -118,7 +122,7 @@ func isIdent(ch rune) bool {
 	if isDigit(ch) {
 		return true
 	}
-	if ch == '.' || ch == '-' || ch == '_' {
+	if ch == '.' || ch == '-' || ch == '_' || ch == '$' || ch == ':' {
 		return true
 	}
 	return false
@@ -128,37 +132,77 @@ func isDigit(ch rune) bool {
 	return ch >= '0' && ch <= '9'
 }
 
+// Pos is a position in a source file. Line and column start at 1.
+type Pos struct {
+	File string
+	Line int
+	Col  int
+}
+
+func (p Pos) String() string {
+	if p.File == "" {
+		return fmt.Sprintf("%d:%d", p.Line, p.Col)
+	}
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Col)
+}
+
 type Scanner struct {
 	r *bufio.Reader
 
-	i int
+	line    int
+	col     int
+	prevCol int
+	last    rune
 }
 
 func NewScanner(r io.Reader) *Scanner {
 	return &Scanner{
 		r: bufio.NewReader(r),
 
-		i: -1,
+		line: 1,
+		col:  1,
 	}
 }
 
 func (s *Scanner) read() (rune, error) {
-	s.i++
 	ch, _, err := s.r.ReadRune()
 	if err != nil {
 		if err == io.EOF {
+			s.last = eof
 			return eof, nil
 		}
 		return eof, err
 	}
+	s.last = ch
+	s.prevCol = s.col
+	if ch == '\n' {
+		s.line++
+		s.col = 1
+	} else {
+		s.col++
+	}
 	return ch, nil
 }
 
 func (s *Scanner) unread() error {
-	s.i--
+	if s.last == eof {
+		return nil
+	}
+	if s.last == '\n' {
+		s.line--
+	}
+	s.col = s.prevCol
 	return s.r.UnreadRune()
 }
 
+// Pos returns the position of the next character. The file is left empty.
+func (s *Scanner) Pos() Pos {
+	return Pos{
+		Line: s.line,
+		Col:  s.col,
+	}
+}
+
 func (s *Scanner) scanWhitespace() (tok Token, lit string, err error) {
 	var buf bytes.Buffer
 	ch, err := s.read()
@@ -240,25 +284,27 @@ func (s *Scanner) scanDigit() (tok Token, lit string, err error) {
 	return VALUE, buf.String(), nil
 }
 
+// scanComment scans the rest of the line. The line break is left for the
+// whitespace scanner.
 func (s *Scanner) scanComment() (tok Token, lit string, err error) {
 	var buf bytes.Buffer
-	ch, err := s.read()
-	if err != nil {
-		return ILLEGAL, "", err
-	}
-	buf.WriteRune(ch)
-
 	for {
 		if ch, err := s.read(); err != nil {
 			return ILLEGAL, "", err
-		} else if ch == eof || ch == '\n' || ch == '\r' {
+		} else if ch == eof {
+			break
+		} else if ch == '\n' || ch == '\r' {
+			err = s.unread()
+			if err != nil {
+				return ILLEGAL, "", err
+			}
 			break
 		} else {
 			buf.WriteRune(ch)
 		}
 	}
 
-	return COMMENT, buf.String(), nil
+	return COMMENT, strings.TrimSpace(buf.String()), nil
 }
 
 func (s *Scanner) Scan() (tok Token, lit string, err error) {
@@ -290,11 +336,7 @@ func (s *Scanner) Scan() (tok Token, lit string, err error) {
 			return ILLEGAL, "", err
 		}
 		if next != '/' {
-			return ILLEGAL, "", fmt.Errorf("invalid single / on index %d", s.i)
-		}
-		_, _, err = s.scanWhitespace()
-		if err != nil {
-			return ILLEGAL, "", err
+			return ILLEGAL, "", fmt.Errorf("invalid single /")
 		}
 		return s.scanComment()
 	case '@':
@@ -303,6 +345,8 @@ func (s *Scanner) Scan() (tok Token, lit string, err error) {
 		return EQUALS, "=", nil
 	case ';':
 		return SEMICOLON, ";", nil
+	case '+', '&', '|', '!':
+		return OPERATOR, string(ch), nil
 	case '(':
 		return LABEL_START, "(", nil
 	case ')':
@@ -199,3 +199,36 @@ M=D
 		},
 	})
 }
+
+func TestScanEmptyComment(t *testing.T) {
+	runExpectations(t, []testcase{
+		{
+			input: `
+D=M //
+@END
+			`,
+			expect: []expectation{
+				{
+					tok: VALUE,
+					lit: "D",
+				},
+				{
+					tok: EQUALS,
+					lit: "=",
+				},
+				{
+					tok: VALUE,
+					lit: "M",
+				},
+				{
+					tok: AT,
+					lit: "@",
+				},
+				{
+					tok: VALUE,
+					lit: "END",
+				},
+			},
+		},
+	})
+}
@@ -3,34 +3,79 @@ package language
 import (
 	"fmt"
 	"io"
+	"strings"
 )
 
 type Command interface {
 	fmt.Stringer
+	// Pos returns the source position of the command
+	Pos() Pos
 	Translate(*SymbolTable, io.Writer) error
 }
 
+// node holds the source position of a command
+type node struct {
+	pos Pos
+}
+
+// Pos returns the source position
+func (n node) Pos() Pos {
+	return n.pos
+}
+
 type Parser struct {
-	s   *Scanner
-	buf struct {
+	s    *Scanner
+	file string
+	buf  struct {
 		tok         Token
 		lit         string
+		pos         Pos
 		isUnscanned bool
 	}
 	i int
 
-	err  error
+	errs ErrorList
 	tree []Command
 }
 
+// Error is an error at a source position, formatted as file:line:col: message
+type Error struct {
+	Pos
+	Err error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %v", e.Pos, e.Err)
+}
+
+// ErrorList is returned by Run and holds all errors of the input in
+// source order, one per line at most
+type ErrorList []*Error
+
+func (l ErrorList) Error() string {
+	msgs := make([]string, len(l))
+	for i, err := range l {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
 type ParserContext struct {
+	// pos is the position of the current command
+	pos Pos
 }
 
 type stateFunc func(p *Parser, ctx ParserContext) (ParserContext, stateFunc)
 
 func NewParser(r io.Reader) *Parser {
+	return NewFileParser(r, "")
+}
+
+// NewFileParser creates a parser, which reports positions in the named file
+func NewFileParser(r io.Reader, fileName string) *Parser {
 	return &Parser{
 		s:    NewScanner(r),
+		file: fileName,
 		i:    -1,
 		tree: make([]Command, 0),
 	}
@@ -43,8 +88,12 @@ func (p *Parser) scan() (tok Token, lit string, err error) {
 		return p.buf.tok, p.buf.lit, nil
 	}
 
+	p.buf.pos = p.s.Pos()
+	p.buf.pos.File = p.file
 	tok, lit, err = p.s.Scan()
 	if err != nil {
+		p.buf.tok = ILLEGAL
+		p.buf.lit = ""
 		return ILLEGAL, "", err
 	}
 
@@ -83,30 +132,83 @@ func (p *Parser) Run() error {
 	for state := top; state != nil; {
 		ctx, state = state(p, ctx)
 	}
-	if p.err != nil {
-		return fmt.Errorf("parse error on token %d (index %d): %v", p.i, p.s.i, p.err)
+	if len(p.errs) > 0 {
+		return p.errs
 	}
 	return nil
 }
 
+// parseError records the error on the last scanned token and recovers
 func parseError(err error) stateFunc {
 	return func(p *Parser, ctx ParserContext) (ParserContext, stateFunc) {
-		p.err = err
-		return ctx, nil
+		perr, ok := err.(*Error)
+		if !ok {
+			perr = &Error{
+				Pos: p.buf.pos,
+				Err: err,
+			}
+		}
+		p.errs = append(p.errs, perr)
+		return ctx, recoverLine
+	}
+}
+
+// recoverLine skips the rest of the line of the invalid command and
+// continues parsing on the next line. Invalid input on the rest of the
+// line is not reported.
+func recoverLine(p *Parser, ctx ParserContext) (ParserContext, stateFunc) {
+	if !p.buf.isUnscanned {
+		switch {
+		case p.endsLine():
+			return ctx, top
+		case p.buf.tok != ILLEGAL && p.buf.pos.Line > ctx.pos.Line:
+			// the offending token already belongs to the next line
+			p.unscan()
+			return ctx, top
+		}
+	}
+	for {
+		before := p.s.Pos()
+		tok, _, err := p.scan()
+		switch {
+		case err != nil && p.s.Pos() == before:
+			// the input cannot be read any further
+			return ctx, nil
+		case tok == EOF:
+			return ctx, nil
+		case p.endsLine():
+			return ctx, top
+		}
 	}
 }
 
+// endsLine returns true if the last scanned token ends its line
+func (p *Parser) endsLine() bool {
+	if p.buf.tok == WS {
+		return strings.ContainsRune(p.buf.lit, '\n')
+	}
+	// invalid input may include the line break
+	return p.buf.tok == ILLEGAL && p.s.Pos().Line > p.buf.pos.Line
+}
+
 func top(p *Parser, ctx ParserContext) (ParserContext, stateFunc) {
 	tok, lit, err := p.scanIgnore()
 	if err != nil {
 		return ctx, parseError(err)
 	}
+	ctx.pos = p.buf.pos
 	switch true {
 	case tok == EOF:
 		return ctx, nil
 	case tok == AT:
 		p.unscan()
 		return ctx, parseAInstruction
+	case tok == LABEL_START:
+		p.unscan()
+		return ctx, parseLabel
+	case tok == VALUE || tok == OPERATOR:
+		p.unscan()
+		return ctx, parseCInstruction
 	}
 
 	return ctx, parseError(fmt.Errorf("invalid token %s (%s)", tok, lit))
@@ -55,6 +55,25 @@ func (t *SymbolTable) Label(str string) int {
 	return t.addrs[str]
 }
 
+// Lookup returns the address of a label or variable without allocating
+// unknown symbols
+func (t *SymbolTable) Lookup(str string) (int, bool) {
+	if label, ok := t.labels[str]; ok {
+		return label, true
+	}
+	addr, ok := t.addrs[str]
+	return addr, ok
+}
+
+// Labels returns the ROM addresses of the labels
+func (t *SymbolTable) Labels() map[string]int {
+	labels := make(map[string]int, len(t.labels))
+	for name, addr := range t.labels {
+		labels[name] = addr
+	}
+	return labels
+}
+
 func (t *SymbolTable) RegisterLabel(str string) int {
 	current := t.instruction
 	t.labels[str] = current
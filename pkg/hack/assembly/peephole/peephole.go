@@ -0,0 +1,347 @@
+// Package peephole implements a peephole optimizer for Hack assembly.
+//
+// The optimizer works on the parsed instruction stream and replaces
+// short instruction sequences with cheaper ones of the same effect on
+// the registers, the memory and the control flow. Sequences never span
+// a label, so jumps into the middle of a replaced sequence are not
+// possible.
+package peephole
+
+import (
+	"strings"
+
+	"github.com/wongak/nand2tetris/pkg/hack/assembly/language"
+)
+
+// rule matches at the start of cmds. It returns the number of commands
+// replaced and their replacement, or 0 if it does not match.
+type rule func(cmds []language.Command) (int, []language.Command)
+
+var rules = []rule{
+	pushPopD,
+	pushPopAddress,
+	stackUpDown,
+	storeReload,
+	indirectReload,
+	redundantLoad,
+	deadLoad,
+	jumpNext,
+}
+
+// Optimize applies the rules until no rule matches anymore. Comments
+// are removed, so they do not hide instruction sequences and do not
+// describe replaced instructions.
+func Optimize(cmds []language.Command) []language.Command {
+	cmds = stripComments(cmds)
+	for {
+		out, changed := pass(cmds)
+		if !changed {
+			return out
+		}
+		cmds = out
+	}
+}
+
+func pass(cmds []language.Command) ([]language.Command, bool) {
+	out := make([]language.Command, 0, len(cmds))
+	var changed bool
+	for i := 0; i < len(cmds); {
+		n, repl := match(cmds[i:])
+		if n == 0 {
+			out = append(out, cmds[i])
+			i++
+			continue
+		}
+		out = append(out, repl...)
+		i += n
+		changed = true
+	}
+	return out, changed
+}
+
+func stripComments(cmds []language.Command) []language.Command {
+	out := make([]language.Command, 0, len(cmds))
+	for _, cmd := range cmds {
+		switch c := cmd.(type) {
+		case *language.Comment:
+		case *language.CInstruction:
+			if c.Comment != "" {
+				stripped := *c
+				stripped.Comment = ""
+				cmd = &stripped
+			}
+			out = append(out, cmd)
+		default:
+			out = append(out, cmd)
+		}
+	}
+	return out
+}
+
+func match(cmds []language.Command) (int, []language.Command) {
+	for _, r := range rules {
+		if n, repl := r(cmds); n > 0 {
+			return n, repl
+		}
+	}
+	return 0, nil
+}
+
+// Count returns the number of instructions, i.e. commands without labels
+// and comments
+func Count(cmds []language.Command) int {
+	var n int
+	for _, cmd := range cmds {
+		switch cmd.(type) {
+		case *language.Label, *language.Comment:
+		default:
+			n++
+		}
+	}
+	return n
+}
+
+// isA reports whether cmd i is the A-instruction @addr
+func isA(cmds []language.Command, i int, addr string) bool {
+	if i >= len(cmds) {
+		return false
+	}
+	a, ok := cmds[i].(*language.AInstruction)
+	return ok && a.Address == addr
+}
+
+// isC reports whether cmd i is the C-instruction dest=comp without jump
+func isC(cmds []language.Command, i int, dest, comp string) bool {
+	if i >= len(cmds) {
+		return false
+	}
+	c, ok := cmds[i].(*language.CInstruction)
+	return ok && c.Dest == dest && c.Comp == comp && c.Jump == language.NULL
+}
+
+// address returns the address of the A-instruction i
+func address(cmds []language.Command, i int) (string, bool) {
+	if i >= len(cmds) {
+		return "", false
+	}
+	a, ok := cmds[i].(*language.AInstruction)
+	if !ok {
+		return "", false
+	}
+	return a.Address, true
+}
+
+// isPush returns the length of the push of D onto the stack at cmd i,
+// or 0
+//
+//	@SP, A=M, M=D, @SP, M=M+1
+func isPush(cmds []language.Command, i int) int {
+	if isA(cmds, i, "SP") &&
+		isC(cmds, i+1, "A", "M") &&
+		isC(cmds, i+2, "M", "D") &&
+		isA(cmds, i+3, "SP") &&
+		isC(cmds, i+4, "M", "M+1") {
+		return 5
+	}
+	return 0
+}
+
+// isPop returns the length of the pop from the stack into D at cmd i,
+// or 0
+//
+//	@SP, M=M-1, A=M, D=M
+//	@SP, AM=M-1, D=M
+func isPop(cmds []language.Command, i int) int {
+	if !isA(cmds, i, "SP") {
+		return 0
+	}
+	if isC(cmds, i+1, "M", "M-1") && isC(cmds, i+2, "A", "M") && isC(cmds, i+3, "D", "M") {
+		return 4
+	}
+	if isC(cmds, i+1, "AM", "M-1") && isC(cmds, i+2, "D", "M") {
+		return 3
+	}
+	return 0
+}
+
+// pushPopD removes the push of D followed by the pop into D. The next
+// instruction must load A, which points to the stack after the pop.
+//
+//	push, pop, @X => @X
+func pushPopD(cmds []language.Command) (int, []language.Command) {
+	push := isPush(cmds, 0)
+	if push == 0 {
+		return 0, nil
+	}
+	pop := isPop(cmds, push)
+	if pop == 0 {
+		return 0, nil
+	}
+	if _, ok := address(cmds, push+pop); !ok {
+		return 0, nil
+	}
+	return push + pop, []language.Command{}
+}
+
+// pushPopAddress moves D directly to the address of a pop to a segment
+// instead of through the stack. The pop computes the address into R13
+// first, so D is kept in R14 meanwhile. As for pushPopD, the next
+// instruction must load A.
+//
+//	push, @i, D=A, @SEG, D=D+M, @R13, M=D, pop
+//	  => @R14, M=D, @i, D=A, @SEG, D=D+M, @R13, M=D, @R14, D=M
+//
+// where D=D+M may also be D=D+A for the fixed temp segment.
+func pushPopAddress(cmds []language.Command) (int, []language.Command) {
+	push := isPush(cmds, 0)
+	if push == 0 {
+		return 0, nil
+	}
+	addr := cmds[push:]
+	if _, ok := address(addr, 0); !ok || !isC(addr, 1, "D", "A") {
+		return 0, nil
+	}
+	if _, ok := address(addr, 2); !ok || !(isC(addr, 3, "D", "D+M") || isC(addr, 3, "D", "D+A")) {
+		return 0, nil
+	}
+	if !isA(addr, 4, "R13") || !isC(addr, 5, "M", "D") {
+		return 0, nil
+	}
+	pop := isPop(addr, 6)
+	if pop == 0 {
+		return 0, nil
+	}
+	if _, ok := address(addr, 6+pop); !ok {
+		return 0, nil
+	}
+	repl := []language.Command{
+		&language.AInstruction{Address: "R14"},
+		&language.CInstruction{Dest: "M", Comp: "D", Jump: language.NULL},
+	}
+	repl = append(repl, addr[:6]...)
+	repl = append(repl,
+		&language.AInstruction{Address: "R14"},
+		&language.CInstruction{Dest: "D", Comp: "M", Jump: language.NULL},
+	)
+	return push + 6 + pop, repl
+}
+
+// stackUpDown removes a push immediately followed by a pop of the
+// stack pointer
+//
+//	@SP, M=M+1, @SP, M=M-1 => @SP
+func stackUpDown(cmds []language.Command) (int, []language.Command) {
+	if isA(cmds, 0, "SP") &&
+		isC(cmds, 1, "M", "M+1") &&
+		isA(cmds, 2, "SP") &&
+		isC(cmds, 3, "M", "M-1") {
+		return 4, cmds[:1]
+	}
+	return 0, nil
+}
+
+// storeReload removes the load of a value just stored
+//
+//	M=D, D=M => M=D
+func storeReload(cmds []language.Command) (int, []language.Command) {
+	if isC(cmds, 0, "M", "D") && isC(cmds, 1, "D", "M") {
+		return 2, cmds[:1]
+	}
+	return 0, nil
+}
+
+// indirectReload removes the load of a value just stored through a pointer
+//
+//	@X, A=M, M=D, @X, A=M, D=M => @X, A=M, M=D
+func indirectReload(cmds []language.Command) (int, []language.Command) {
+	x, ok := address(cmds, 0)
+	if ok &&
+		isC(cmds, 1, "A", "M") &&
+		isC(cmds, 2, "M", "D") &&
+		isA(cmds, 3, x) &&
+		isC(cmds, 4, "A", "M") &&
+		isC(cmds, 5, "D", "M") {
+		return 6, cmds[:3]
+	}
+	return 0, nil
+}
+
+// redundantLoad removes an A-instruction loading the address already in A
+//
+//	@X, dest=comp, @X => @X, dest=comp
+//
+// where dest does not contain A and there is no jump.
+func redundantLoad(cmds []language.Command) (int, []language.Command) {
+	x, ok := address(cmds, 0)
+	if !ok || len(cmds) < 3 {
+		return 0, nil
+	}
+	c, ok := cmds[1].(*language.CInstruction)
+	if !ok || c.Jump != language.NULL || strings.ContainsRune(c.Dest, 'A') {
+		return 0, nil
+	}
+	if !isA(cmds, 2, x) {
+		return 0, nil
+	}
+	return 3, cmds[:2]
+}
+
+// deadLoad removes an A-instruction which is overwritten by the next
+// instruction. Labels in between do not matter, as every jump to them
+// sets A itself.
+//
+//	@X, (L)..., @Y => (L)..., @Y
+func deadLoad(cmds []language.Command) (int, []language.Command) {
+	if _, ok := address(cmds, 0); !ok {
+		return 0, nil
+	}
+	i := 1
+	for i < len(cmds) {
+		if _, ok := cmds[i].(*language.Label); !ok {
+			break
+		}
+		i++
+	}
+	if _, ok := address(cmds, i); !ok {
+		return 0, nil
+	}
+	return 1, nil
+}
+
+// jumpNext removes a jump to one of the labels directly following it.
+// The A-instruction is kept, A is set either way. Instructions writing
+// A are left alone.
+//
+//	@L, comp;JMP, (L) => @L, (L)
+//	@L, dest=comp;JMP, (L) => @L, dest=comp, (L)
+func jumpNext(cmds []language.Command) (int, []language.Command) {
+	l, ok := address(cmds, 0)
+	if !ok || len(cmds) < 3 {
+		return 0, nil
+	}
+	c, ok := cmds[1].(*language.CInstruction)
+	if !ok || c.Jump == language.NULL || strings.ContainsRune(c.Dest, 'A') {
+		return 0, nil
+	}
+	for _, cmd := range cmds[2:] {
+		label, ok := cmd.(*language.Label)
+		if !ok {
+			return 0, nil
+		}
+		if label.Name != l {
+			continue
+		}
+		if c.Dest == "" {
+			return 2, cmds[:1]
+		}
+		return 2, []language.Command{
+			cmds[0],
+			&language.CInstruction{
+				Dest: c.Dest,
+				Comp: c.Comp,
+				Jump: language.NULL,
+			},
+		}
+	}
+	return 0, nil
+}
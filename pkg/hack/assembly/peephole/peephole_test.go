@@ -0,0 +1,251 @@
+package peephole_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/wongak/nand2tetris/pkg/hack/assembly/language"
+	"github.com/wongak/nand2tetris/pkg/hack/assembly/peephole"
+	"github.com/wongak/nand2tetris/pkg/hack/cpu"
+	vm "github.com/wongak/nand2tetris/pkg/hack/vm/language"
+	"github.com/wongak/nand2tetris/pkg/hack/vm/translator"
+)
+
+func parse(t *testing.T, src string) []language.Command {
+	p := language.NewParser(strings.NewReader(src))
+	err := p.Run()
+	if err != nil {
+		t.Fatalf("error on parse: %v", err)
+	}
+	return p.Tree()
+}
+
+func code(t *testing.T, cmds []language.Command) string {
+	buf := bytes.NewBuffer(nil)
+	err := language.WriteCode(buf, cmds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Join(strings.Fields(buf.String()), " ")
+}
+
+func TestOptimizeRules(t *testing.T) {
+	for _, c := range []struct {
+		input  string
+		expect string
+	}{
+		// push constant 7, pop into D
+		{
+			input:  "@7 D=A @SP A=M M=D @SP M=M+1 @SP M=M-1 A=M D=M @R13 M=D",
+			expect: "@7 D=A @R13 M=D",
+		},
+		{
+			input:  "@X M=D @X D=M D=D+1",
+			expect: "@X M=D D=D+1",
+		},
+		// A is written, @X is not redundant
+		{
+			input:  "@X A=M @X D=M",
+			expect: "@X A=M @X D=M",
+		},
+		{
+			input:  "@1 (L1) (L2) @2 D=A",
+			expect: "(L1) (L2) @2 D=A",
+		},
+		{
+			input:  "@END 0;JMP (L) (END) @R13 M=D",
+			expect: "(L) (END) @R13 M=D",
+		},
+		{
+			input:  "@END D=D-1;JNE (END) D=A",
+			expect: "@END D=D-1 (END) D=A",
+		},
+		// jump back is kept
+		{
+			input:  "(END) @END 0;JMP",
+			expect: "(END) @END 0;JMP",
+		},
+		// jump with A written is kept
+		{
+			input:  "@L A=D;JMP (L)",
+			expect: "@L A=D;JMP (L)",
+		},
+	} {
+		out := peephole.Optimize(parse(t, strings.ReplaceAll(c.input, " ", "\n")))
+		if code(t, out) != c.expect {
+			t.Errorf("unexpected optimization of %s. expect\n%s, got\n%s", c.input, c.expect, code(t, out))
+		}
+	}
+}
+
+const testProgram = `
+function Sys.init 2
+	push constant 0
+	pop local 0
+	push constant 0
+	pop local 1
+label LOOP
+	push local 0
+	push constant 10
+	eq
+	if-goto END
+	push local 0
+	push constant 1
+	add
+	pop local 0
+	push local 1
+	push local 0
+	add
+	pop local 1
+	goto LOOP
+label END
+	push constant 3000
+	pop pointer 1
+	push local 1
+	pop that 2
+	push local 1
+	push constant 54
+	gt
+	push local 1
+	push constant 56
+	lt
+	and
+	not
+	pop temp 3
+	push constant 7
+	neg
+	call Sys.id 1
+	pop static 0
+label HALT
+	goto HALT
+function Sys.id 0
+	push argument 0
+	return
+`
+
+func run(t *testing.T, cmds []language.Command) *cpu.Machine {
+	buf := bytes.NewBuffer(nil)
+	err := language.Assemble(language.NewSymbolTable(), cmds, buf)
+	if err != nil {
+		t.Fatalf("error on assemble: %v", err)
+	}
+	m := cpu.NewMachine()
+	err = m.Load(buf)
+	if err != nil {
+		t.Fatalf("error on load: %v", err)
+	}
+	_, err = m.Run(100000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.Halted() {
+		t.Fatal("expect machine to halt")
+	}
+	return m
+}
+
+func TestOptimizeProgram(t *testing.T) {
+	p := vm.NewParser(strings.NewReader(testProgram))
+	tr := translator.New()
+	err := p.Run(tr.SymbolTable(), "Sys")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tr.Bootstrap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tr.Translate(p.Tree())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmds := tr.Program()
+	optimized := peephole.Optimize(cmds)
+	before, after := peephole.Count(cmds), peephole.Count(optimized)
+	t.Logf("%d instructions, %d after optimization", before, after)
+	// the push/pop pairs between VM commands make up most of the savings
+	if saved := before - after; saved*100 < before*12 {
+		t.Errorf("expect at least 12%% fewer instructions, got %d of %d", after, before)
+	}
+
+	for _, cmd := range optimized {
+		switch c := cmd.(type) {
+		case *language.Comment:
+			t.Errorf("expect comments to be removed, got %s", c)
+		case *language.CInstruction:
+			if c.Comment != "" {
+				t.Errorf("expect trailing comments to be removed, got %s // %s", c, c.Comment)
+			}
+		}
+	}
+
+	m := run(t, cmds)
+	o := run(t, optimized)
+	// return addresses on the stack and in R14 differ with the code size
+	for _, r := range [][2]int{{0, 14}, {16, 256}, {3000, 3010}} {
+		for i := r[0]; i < r[1]; i++ {
+			if m.RAM[i] != o.RAM[i] {
+				t.Errorf("RAM[%d] differs: %d, optimized %d", i, m.RAM[i], o.RAM[i])
+			}
+		}
+	}
+	if m.RAM[3002] != 55 {
+		t.Errorf("expect sum 55 in RAM[3002], got %d", m.RAM[3002])
+	}
+	if m.RAM[8] != 0 {
+		t.Errorf("expect not(55 > 54 and 55 < 56) in temp 3, got %d", m.RAM[8])
+	}
+	if m.RAM[16] != -7 {
+		t.Errorf("expect -7 in static 0, got %d", m.RAM[16])
+	}
+}
+
+func TestOptimizePushPop(t *testing.T) {
+	for _, c := range []struct {
+		vm     string
+		expect string
+	}{
+		{
+			"push constant 5\npop static 3",
+			"@5 D=A @Test.3 M=D",
+		},
+		{
+			"push local 0\npop pointer 1",
+			"@0 D=A @LCL D=D+M A=D D=M @THAT M=D",
+		},
+		{
+			"push argument 0\npop local 1",
+			"@0 D=A @ARG D=D+M A=D D=M @R14 M=D @1 D=A @LCL D=D+M @R13 M=D @R14 D=M @R13 A=M M=D",
+		},
+		{
+			"push constant 2\npop temp 2",
+			"@2 D=A @R14 M=D @2 D=A @R5 D=D+A @R13 M=D @R14 D=M @R13 A=M M=D",
+		},
+		{
+			"push local 1\npush constant 2\nadd",
+			"@1 D=A @LCL D=D+M A=D D=M @SP A=M M=D @SP M=M+1 @2 D=A @SP M=M-1 A=M D=D+M M=D @SP M=M+1",
+		},
+		// neg writes the result through A, the value stays on the stack
+		{
+			"push constant 2\nneg",
+			"@2 D=A @SP A=M M=D D=-D M=D @SP M=M+1",
+		},
+	} {
+		p := vm.NewParser(strings.NewReader(c.vm))
+		tr := translator.New()
+		err := p.Run(tr.SymbolTable(), "Test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = tr.Translate(p.Tree())
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := code(t, peephole.Optimize(tr.Program()))
+		if got != c.expect {
+			t.Errorf("unexpected optimization of %q. expect\n%s\ngot\n%s", c.vm, c.expect, got)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package cpu
+
+// control bits of the ALU as found in bits 11 to 6 of a C-instruction
+const (
+	zx = 1 << (5 - iota)
+	nx
+	zy
+	ny
+	f
+	no
+)
+
+// alu computes the ALU output for the inputs x and y and the six control bits c
+func alu(x, y int16, c uint16) int16 {
+	if c&zx != 0 {
+		x = 0
+	}
+	if c&nx != 0 {
+		x = ^x
+	}
+	if c&zy != 0 {
+		y = 0
+	}
+	if c&ny != 0 {
+		y = ^y
+	}
+	var out int16
+	if c&f != 0 {
+		out = x + y
+	} else {
+		out = x & y
+	}
+	if c&no != 0 {
+		out = ^out
+	}
+	return out
+}
+
+// jump evaluates the three jump bits against the ALU output
+func jump(out int16, j uint16) bool {
+	return (j&4 != 0 && out < 0) ||
+		(j&2 != 0 && out == 0) ||
+		(j&1 != 0 && out > 0)
+}
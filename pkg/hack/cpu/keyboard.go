@@ -0,0 +1,87 @@
+package cpu
+
+import "sort"
+
+// Key codes of the Hack keyboard for keys without printable character.
+// Printable characters use their ASCII code.
+const (
+	KeyNewline   int16 = 128
+	KeyBackspace int16 = 129
+	KeyLeft      int16 = 130
+	KeyUp        int16 = 131
+	KeyRight     int16 = 132
+	KeyDown      int16 = 133
+	KeyHome      int16 = 134
+	KeyEnd       int16 = 135
+	KeyPageUp    int16 = 136
+	KeyPageDown  int16 = 137
+	KeyInsert    int16 = 138
+	KeyDelete    int16 = 139
+	KeyEsc       int16 = 140
+	// KeyF1 is the first of the function keys F1 to F12
+	KeyF1 int16 = 141
+)
+
+// KeyCode returns the Hack key code of the character. Newline and
+// backspace map to their keys.
+func KeyCode(ch rune) (int16, bool) {
+	switch {
+	case ch == '\n' || ch == '\r':
+		return KeyNewline, true
+	case ch == '\b' || ch == 0x7f:
+		return KeyBackspace, true
+	case ch >= ' ' && ch <= '~':
+		return int16(ch), true
+	}
+	return 0, false
+}
+
+// KeyEvent sets the keyboard memory map to the key code once the machine
+// executed the given number of cycles since the last reset. Code 0
+// releases the key.
+type KeyEvent struct {
+	Cycle int
+	Code  int16
+}
+
+// QueueKeys adds the events to the keyboard input queue. The events are
+// applied in order of their cycle before the next instruction executes.
+func (m *Machine) QueueKeys(events ...KeyEvent) {
+	m.keys = append(m.keys, events...)
+	sort.SliceStable(m.keys, func(i, j int) bool {
+		return m.keys[i].Cycle < m.keys[j].Cycle
+	})
+}
+
+// TypeKeys queues the characters of the text starting at the cycle.
+// Each key is held for the given number of cycles and released for as
+// long before the next key. It returns the cycle after the last release.
+func (m *Machine) TypeKeys(cycle, hold int, text string) int {
+	events := make([]KeyEvent, 0, 2*len(text))
+	for _, ch := range text {
+		code, ok := KeyCode(ch)
+		if !ok {
+			continue
+		}
+		events = append(events,
+			KeyEvent{Cycle: cycle, Code: code},
+			KeyEvent{Cycle: cycle + hold, Code: 0},
+		)
+		cycle += 2 * hold
+	}
+	m.QueueKeys(events...)
+	return cycle
+}
+
+// PendingKeys returns the number of queued key events
+func (m *Machine) PendingKeys() int {
+	return len(m.keys)
+}
+
+// applyKeys sets the keyboard memory map to the due key events
+func (m *Machine) applyKeys() {
+	for len(m.keys) > 0 && m.keys[0].Cycle <= m.cycles {
+		m.RAM[Keyboard] = m.keys[0].Code
+		m.keys = m.keys[1:]
+	}
+}
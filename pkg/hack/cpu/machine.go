@@ -0,0 +1,183 @@
+package cpu
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	// MemorySize is the number of addressable words of the ROM and the RAM
+	MemorySize = 0x8000
+
+	// Screen is the base address of the screen memory map
+	Screen = 0x4000
+	// Keyboard is the address of the keyboard memory map
+	Keyboard = 0x6000
+)
+
+// Machine models the Hack CPU with its instruction memory (ROM) and
+// data memory (RAM)
+type Machine struct {
+	A  int16
+	D  int16
+	PC uint16
+
+	ROM [MemorySize]uint16
+	RAM [MemorySize]int16
+
+	size   int
+	cycles int
+	halted bool
+	// keys holds the queued key events in order of their cycle
+	keys []KeyEvent
+}
+
+// NewMachine creates a new machine with empty memory
+func NewMachine() *Machine {
+	return &Machine{}
+}
+
+// Load reads a program in the textual .hack format as produced by the
+// assembler. Each line holds one instruction as 16 binary digits.
+func (m *Machine) Load(r io.Reader) error {
+	program := make([]uint16, 0)
+	sc := bufio.NewScanner(r)
+	line := 0
+	for sc.Scan() {
+		line++
+		text := strings.TrimSpace(sc.Text())
+		if text == "" {
+			continue
+		}
+		if len(text) != 16 {
+			return fmt.Errorf("invalid instruction %q on line %d. expect 16 binary digits", text, line)
+		}
+		var instr uint16
+		for _, ch := range text {
+			instr <<= 1
+			switch ch {
+			case '0':
+			case '1':
+				instr |= 1
+			default:
+				return fmt.Errorf("invalid instruction %q on line %d. expect 16 binary digits", text, line)
+			}
+		}
+		program = append(program, instr)
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+	return m.LoadProgram(program)
+}
+
+// LoadProgram copies the program into the ROM and resets the machine
+func (m *Machine) LoadProgram(program []uint16) error {
+	if len(program) > MemorySize {
+		return fmt.Errorf("program of %d instructions does not fit into ROM", len(program))
+	}
+	m.ROM = [MemorySize]uint16{}
+	copy(m.ROM[:], program)
+	m.size = len(program)
+	m.Reset()
+	return nil
+}
+
+// Reset resets the registers. The RAM is left untouched.
+func (m *Machine) Reset() {
+	m.A = 0
+	m.D = 0
+	m.PC = 0
+	m.cycles = 0
+	m.halted = false
+}
+
+// Size returns the number of loaded instructions
+func (m *Machine) Size() int {
+	return m.size
+}
+
+// Cycles returns the number of executed instructions since the last reset
+func (m *Machine) Cycles() int {
+	return m.cycles
+}
+
+// Halted returns true if the machine entered the terminating infinite loop
+// (END) @END 0;JMP
+func (m *Machine) Halted() bool {
+	return m.halted
+}
+
+// address returns the RAM address selected by the A register
+func (m *Machine) address() uint16 {
+	return uint16(m.A) & (MemorySize - 1)
+}
+
+// Step executes a single instruction
+func (m *Machine) Step() error {
+	if m.PC >= MemorySize {
+		return fmt.Errorf("program counter %d out of ROM", m.PC)
+	}
+	m.applyKeys()
+	pc := m.PC
+	instr := m.ROM[pc]
+	m.cycles++
+
+	// A-instruction
+	if instr&0x8000 == 0 {
+		m.A = int16(instr)
+		m.PC++
+		return nil
+	}
+
+	// C-instruction 111a cccc ccdd djjj
+	y := m.A
+	if instr&0x1000 != 0 {
+		y = m.RAM[m.address()]
+	}
+	out := alu(m.D, y, (instr>>6)&0x3f)
+
+	target := uint16(m.A)
+	if instr&0x08 != 0 {
+		m.RAM[m.address()] = out
+	}
+	if instr&0x20 != 0 {
+		m.A = out
+	}
+	if instr&0x10 != 0 {
+		m.D = out
+	}
+
+	if !jump(out, instr&0x07) {
+		m.PC++
+		return nil
+	}
+	m.PC = target
+	if instr&0x3f == 0x07 && m.isLoop(pc, target) {
+		m.halted = true
+	}
+	return nil
+}
+
+// isLoop returns true if an unconditional jump without dest from pc to
+// target does not do anything but jump again
+func (m *Machine) isLoop(pc, target uint16) bool {
+	if target == pc {
+		return true
+	}
+	return target+1 == pc && m.ROM[target] == target
+}
+
+// Run executes the program until it halts or maxCycles instructions were
+// executed. It returns the number of executed instructions.
+func (m *Machine) Run(maxCycles int) (int, error) {
+	i := 0
+	for ; i < maxCycles && !m.halted; i++ {
+		if err := m.Step(); err != nil {
+			return i, err
+		}
+	}
+	return i, nil
+}
@@ -0,0 +1,187 @@
+package cpu_test
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	asm "github.com/wongak/nand2tetris/pkg/hack/assembly/language"
+	"github.com/wongak/nand2tetris/pkg/hack/cpu"
+	vm "github.com/wongak/nand2tetris/pkg/hack/vm/language"
+)
+
+func assemble(t *testing.T, src string) *cpu.Machine {
+	p := asm.NewParser(strings.NewReader(src))
+	err := p.Run()
+	if err != nil {
+		t.Fatalf("error on parse: %v", err)
+	}
+	buf := bytes.NewBuffer(nil)
+	err = asm.Assemble(asm.NewSymbolTable(), p.Tree(), buf)
+	if err != nil {
+		t.Fatalf("error on assemble: %v", err)
+	}
+	m := cpu.NewMachine()
+	err = m.Load(buf)
+	if err != nil {
+		t.Fatalf("error on load: %v", err)
+	}
+	return m
+}
+
+func assembleFile(t *testing.T, fileName string) *cpu.Machine {
+	src, err := os.ReadFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return assemble(t, string(src))
+}
+
+func TestMult(t *testing.T) {
+	for _, fileName := range []string{"../../../mult.asm", "../../../constmult.asm"} {
+		for _, c := range [][3]int16{
+			{0, 0, 0},
+			{1, 0, 0},
+			{3, 1, 3},
+			{2, 4, 8},
+			{6, 7, 42},
+			{123, 45, 5535},
+		} {
+			m := assembleFile(t, fileName)
+			m.RAM[0] = c[0]
+			m.RAM[1] = c[1]
+			m.RAM[2] = -1
+			_, err := m.Run(100000)
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", fileName, err)
+				continue
+			}
+			if !m.Halted() {
+				t.Errorf("%s: expect machine to halt on %d * %d", fileName, c[0], c[1])
+				continue
+			}
+			if m.RAM[2] != c[2] {
+				t.Errorf("%s: expect %d * %d = %d, got %d", fileName, c[0], c[1], c[2], m.RAM[2])
+			}
+		}
+	}
+}
+
+func TestALU(t *testing.T) {
+	m := assemble(t, `
+	@7
+	D=A
+	@100
+	M=D
+	@3
+	D=D-A // 4
+	@101
+	M=D
+	@100
+	D=D&M // 4 & 7
+	@102
+	M=D
+	@100
+	D=!M
+	@103
+	M=D
+	@100
+	MD=-M
+	@104
+	AM=M+1
+	@105
+	M=-1
+	`)
+	_, err := m.Run(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := map[int]int16{
+		100: -7,
+		101: 4,
+		102: 4,
+		103: -8,
+		104: 1,
+		105: -1,
+	}
+	for addr, e := range expect {
+		if m.RAM[addr] != e {
+			t.Errorf("expect RAM[%d] = %d, got %d", addr, e, m.RAM[addr])
+		}
+	}
+}
+
+func TestScreenAndKeyboard(t *testing.T) {
+	m := assemble(t, `
+	@KBD
+	D=M
+	@SCREEN
+	M=D
+	`)
+	m.RAM[cpu.Keyboard] = 75
+	_, err := m.Run(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.RAM[cpu.Screen] != 75 {
+		t.Errorf("expect screen word 75, got %d", m.RAM[cpu.Screen])
+	}
+}
+
+func TestVMTranslation(t *testing.T) {
+	p := vm.NewParser(strings.NewReader(`
+	push constant 7
+	push constant 8
+	add
+	push constant 20
+	sub
+	push constant 3
+	push constant 3
+	eq
+	`))
+	table := vm.NewSymbolTable()
+	err := p.Run(table, "Test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := bytes.NewBuffer(nil)
+	for _, cmd := range p.Tree() {
+		code, err := cmd.Translate(table)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = asm.WriteCode(buf, code)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	buf.WriteString("(END)\n@END\n0;JMP\n")
+
+	m := assemble(t, buf.String())
+	m.RAM[0] = 256
+	_, err = m.Run(1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.Halted() {
+		t.Fatal("expect machine to halt")
+	}
+	if m.RAM[0] != 258 {
+		t.Errorf("expect SP 258, got %d", m.RAM[0])
+	}
+	if m.RAM[256] != -5 {
+		t.Errorf("expect 7 + 8 - 20 = -5, got %d", m.RAM[256])
+	}
+	if m.RAM[257] != -1 {
+		t.Errorf("expect 3 = 3 to be true, got %d", m.RAM[257])
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	m := cpu.NewMachine()
+	err := m.Load(strings.NewReader("0000000000000001\n000000000000002\n"))
+	if err == nil {
+		t.Error("expect error on invalid instruction")
+	}
+}
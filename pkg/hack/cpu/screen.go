@@ -0,0 +1,75 @@
+package cpu
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+)
+
+const (
+	// ScreenWidth is the number of pixels per screen row
+	ScreenWidth = 512
+	// ScreenHeight is the number of screen rows
+	ScreenHeight = 256
+
+	// wordsPerRow is the number of words of the screen memory map per row
+	wordsPerRow = ScreenWidth / 16
+)
+
+// palette holds the colors of the screen, a set bit is a black pixel
+var palette = color.Palette{color.White, color.Black}
+
+// Pixel returns true if the pixel in column x and row y is black. Bit i
+// of a word of the screen memory map is the pixel i of its 16 columns.
+func (m *Machine) Pixel(x, y int) bool {
+	if x < 0 || x >= ScreenWidth || y < 0 || y >= ScreenHeight {
+		return false
+	}
+	word := uint16(m.RAM[Screen+y*wordsPerRow+x/16])
+	return word&(1<<(x%16)) != 0
+}
+
+// Frame renders the screen memory map to an image
+func (m *Machine) Frame() *image.Paletted {
+	img := image.NewPaletted(image.Rect(0, 0, ScreenWidth, ScreenHeight), palette)
+	for y := 0; y < ScreenHeight; y++ {
+		for x := 0; x < ScreenWidth; x++ {
+			if m.Pixel(x, y) {
+				img.SetColorIndex(x, y, 1)
+			}
+		}
+	}
+	return img
+}
+
+// WritePNG writes the screen as PNG image
+func (m *Machine) WritePNG(wr io.Writer) error {
+	return png.Encode(wr, m.Frame())
+}
+
+// WritePBM writes the screen as binary PBM (P4) image
+func (m *Machine) WritePBM(wr io.Writer) error {
+	w := bufio.NewWriter(wr)
+	fmt.Fprintf(w, "P4\n%d %d\n", ScreenWidth, ScreenHeight)
+	for i := 0; i < ScreenHeight*wordsPerRow; i++ {
+		// PBM starts with the most significant bit, the screen with the
+		// least significant
+		word := reverse(uint16(m.RAM[Screen+i]))
+		w.WriteByte(byte(word >> 8))
+		w.WriteByte(byte(word))
+	}
+	return w.Flush()
+}
+
+// reverse returns the word with the bits in reverse order
+func reverse(word uint16) uint16 {
+	var r uint16
+	for i := 0; i < 16; i++ {
+		r = r<<1 | word&1
+		word >>= 1
+	}
+	return r
+}
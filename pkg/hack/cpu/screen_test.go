@@ -0,0 +1,179 @@
+package cpu_test
+
+import (
+	"bytes"
+	"image/png"
+	"testing"
+
+	"github.com/wongak/nand2tetris/pkg/hack/cpu"
+)
+
+// blackPixels returns the number of black pixels on the screen
+func blackPixels(m *cpu.Machine) int {
+	n := 0
+	for y := 0; y < cpu.ScreenHeight; y++ {
+		for x := 0; x < cpu.ScreenWidth; x++ {
+			if m.Pixel(x, y) {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func TestScreen(t *testing.T) {
+	m := assemble(t, `
+	@SCREEN
+	M=1
+	@SCREEN
+	D=A
+	@321 // row 10, word 1
+	A=D+A
+	M=-1
+	@32767
+	D=A
+	@24575 // last word
+	M=D+1
+(END)
+	@END
+	0;JMP
+`)
+	_, err := m.Run(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		x, y  int
+		black bool
+	}{
+		{0, 0, true},
+		{1, 0, false},
+		{15, 10, false},
+		{16, 10, true},
+		{31, 10, true},
+		{32, 10, false},
+		{510, 255, false},
+		{511, 255, true},
+		{-1, 0, false},
+	} {
+		if m.Pixel(c.x, c.y) != c.black {
+			t.Errorf("expect pixel %d,%d black %t", c.x, c.y, c.black)
+		}
+	}
+	if n := blackPixels(m); n != 18 {
+		t.Errorf("expect 18 black pixels, got %d", n)
+	}
+
+	buf := bytes.NewBuffer(nil)
+	err = m.WritePBM(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := "P4\n512 256\n"
+	pbm := buf.Bytes()
+	if len(pbm) != len(header)+512*256/8 || string(pbm[:len(header)]) != header {
+		t.Fatalf("expect PBM of 512x256, got %d bytes", len(pbm))
+	}
+	pbm = pbm[len(header):]
+	for i, expect := range map[int]byte{0: 0x80, 1: 0, 10*64 + 2: 0xff, 10*64 + 3: 0xff, len(pbm) - 1: 0x01} {
+		if pbm[i] != expect {
+			t.Errorf("expect PBM byte %d %#x, got %#x", i, expect, pbm[i])
+		}
+	}
+
+	buf.Reset()
+	err = m.WritePNG(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds().Dx() != cpu.ScreenWidth || img.Bounds().Dy() != cpu.ScreenHeight {
+		t.Fatalf("expect 512x256 image, got %v", img.Bounds())
+	}
+	if r, _, _, _ := img.At(0, 0).RGBA(); r != 0 {
+		t.Error("expect black pixel 0,0")
+	}
+	if r, _, _, _ := img.At(1, 0).RGBA(); r == 0 {
+		t.Error("expect white pixel 1,0")
+	}
+}
+
+func TestFill(t *testing.T) {
+	m := assembleFile(t, "../../../fill.asm")
+	m.QueueKeys(
+		cpu.KeyEvent{Cycle: 1000, Code: 'A'},
+		cpu.KeyEvent{Cycle: 300000, Code: 0},
+	)
+
+	_, err := m.Run(1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if blackPixels(m) != 0 {
+		t.Fatal("expect white screen without key")
+	}
+	_, err = m.Run(200000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.RAM[cpu.Keyboard] != 'A' {
+		t.Errorf("expect key A, got %d", m.RAM[cpu.Keyboard])
+	}
+	if n := blackPixels(m); n != cpu.ScreenWidth*cpu.ScreenHeight {
+		t.Fatalf("expect black screen on key press, got %d black pixels", n)
+	}
+	_, err = m.Run(300000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.PendingKeys() != 0 || m.RAM[cpu.Keyboard] != 0 {
+		t.Error("expect released key")
+	}
+	if n := blackPixels(m); n != 0 {
+		t.Errorf("expect white screen on key release, got %d black pixels", n)
+	}
+}
+
+func TestTypeKeys(t *testing.T) {
+	// store the key codes in RAM[0..] as they are pressed
+	m := assemble(t, `
+(WAIT)
+	@KBD
+	D=M
+	@WAIT
+	D;JEQ
+	@R15
+	A=M
+	M=D
+	@R15
+	M=M+1
+(RELEASE)
+	@KBD
+	D=M
+	@RELEASE
+	D;JNE
+	@WAIT
+	0;JMP
+`)
+	m.RAM[15] = 0
+	end := m.TypeKeys(10, 50, "Hi\n")
+	if end != 310 {
+		t.Errorf("expect end at cycle 310, got %d", end)
+	}
+	_, err := m.Run(end + 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []int16{'H', 'i', cpu.KeyNewline}
+	for i, code := range expect {
+		if m.RAM[i] != code {
+			t.Errorf("expect key %d at %d, got %d", code, i, m.RAM[i])
+		}
+	}
+	if m.RAM[15] != int16(len(expect)) {
+		t.Errorf("expect %d keys, got %d", len(expect), m.RAM[15])
+	}
+}
@@ -0,0 +1,366 @@
+// Package debugger implements a debugger for Hack machine code. It
+// stops on breakpoints at ROM addresses, labels or VM source lines and
+// steps single instructions or, with a source map, VM commands.
+package debugger
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/wongak/nand2tetris/pkg/hack/assembly/disassembler"
+	asm "github.com/wongak/nand2tetris/pkg/hack/assembly/language"
+	"github.com/wongak/nand2tetris/pkg/hack/cpu"
+	"github.com/wongak/nand2tetris/pkg/hack/vm/translator"
+)
+
+// Program is the machine code to debug with its symbols
+type Program struct {
+	Code []uint16
+	// Labels holds the ROM addresses of the labels
+	Labels map[string]int
+	// Statics holds the static variables of a translated VM program
+	Statics []Static
+	// Source maps ROM addresses to VM commands. It is nil for programs
+	// without VM source.
+	Source translator.SourceMap
+}
+
+// Static is a static variable File.i of a VM program at its RAM address
+type Static struct {
+	Name string
+	Addr int
+}
+
+// Assembled returns the program of the assembly commands
+func Assembled(cmds []asm.Command) (*Program, error) {
+	symbols := asm.NewSymbolTable()
+	buf := bytes.NewBuffer(nil)
+	err := asm.Assemble(symbols, cmds, buf)
+	if err != nil {
+		return nil, err
+	}
+	code, err := disassembler.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+	return &Program{
+		Code:   code,
+		Labels: symbols.Labels(),
+	}, nil
+}
+
+// Translated returns the program of the translator with the static
+// variables and the source map of the VM commands
+func Translated(tr *translator.Translator) (*Program, error) {
+	buf := bytes.NewBuffer(nil)
+	symbols, err := tr.Assemble(buf)
+	if err != nil {
+		return nil, err
+	}
+	code, err := disassembler.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+	p := &Program{
+		Code:   code,
+		Labels: symbols.Labels(),
+		Source: tr.SourceMap(),
+	}
+	for _, s := range tr.SymbolTable().Statics() {
+		addr, ok := symbols.Lookup(s.Symbol)
+		if !ok {
+			return nil, fmt.Errorf("unresolved static symbol %s", s.Symbol)
+		}
+		p.Statics = append(p.Statics, Static{
+			Name: fmt.Sprintf("%s.%d", s.File, s.Index),
+			Addr: addr,
+		})
+	}
+	return p, nil
+}
+
+// Stop is the reason the execution stopped
+type Stop int
+
+const (
+	// Stepped stops after a step
+	Stepped Stop = iota
+	// Breakpoint stops before the instruction of a breakpoint
+	Breakpoint
+	// Halted stops in the terminating infinite loop
+	Halted
+	// Limit stops after the maximum number of instructions
+	Limit
+)
+
+func (s Stop) String() string {
+	switch s {
+	case Stepped:
+		return "stepped"
+	case Breakpoint:
+		return "breakpoint"
+	case Halted:
+		return "halted"
+	case Limit:
+		return "limit"
+	}
+	return "unknown"
+}
+
+type label struct {
+	name string
+	addr int
+}
+
+// Debugger runs a program on the CPU emulator
+type Debugger struct {
+	Machine *cpu.Machine
+
+	program     *Program
+	breakpoints map[int]bool
+	// labels sorted by address
+	labels []label
+	// locals holds the number of local variables by function
+	locals map[string]int
+}
+
+// New loads the program into a new machine
+func New(p *Program) (*Debugger, error) {
+	m := cpu.NewMachine()
+	err := m.LoadProgram(p.Code)
+	if err != nil {
+		return nil, err
+	}
+	d := &Debugger{
+		Machine:     m,
+		program:     p,
+		breakpoints: make(map[int]bool),
+		locals:      make(map[string]int),
+	}
+	for name, addr := range p.Labels {
+		d.labels = append(d.labels, label{name: name, addr: addr})
+	}
+	sort.Slice(d.labels, func(i, j int) bool {
+		if d.labels[i].addr != d.labels[j].addr {
+			return d.labels[i].addr < d.labels[j].addr
+		}
+		return d.labels[i].name < d.labels[j].name
+	})
+	for _, s := range p.Source {
+		if s.Locals > 0 {
+			d.locals[s.Function] = s.Locals
+		}
+	}
+	return d, nil
+}
+
+// Program returns the debugged program
+func (d *Debugger) Program() *Program {
+	return d.program
+}
+
+// Reset clears the RAM and restarts the program
+func (d *Debugger) Reset() {
+	d.Machine.RAM = [cpu.MemorySize]int16{}
+	d.Machine.Reset()
+}
+
+// Resolve returns the ROM address of a location: an address, a label or
+// a VM source line file:line
+func (d *Debugger) Resolve(location string) (int, error) {
+	if addr, err := strconv.Atoi(location); err == nil {
+		if addr < 0 || addr >= len(d.program.Code) {
+			return 0, fmt.Errorf("address %d out of program", addr)
+		}
+		return addr, nil
+	}
+	if addr, ok := d.program.Labels[location]; ok {
+		return addr, nil
+	}
+	if i := strings.LastIndex(location, ":"); i >= 0 {
+		line, err := strconv.Atoi(location[i+1:])
+		if err == nil {
+			if d.program.Source == nil {
+				return 0, fmt.Errorf("no VM source for %s", location)
+			}
+			addr, ok := d.program.Source.Address(location[:i], line)
+			if !ok {
+				return 0, fmt.Errorf("no VM command on %s", location)
+			}
+			return addr, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown location %s", location)
+}
+
+// Break sets a breakpoint on the ROM address
+func (d *Debugger) Break(addr int) {
+	d.breakpoints[addr] = true
+}
+
+// Clear removes the breakpoint on the ROM address
+func (d *Debugger) Clear(addr int) {
+	delete(d.breakpoints, addr)
+}
+
+// Breakpoints returns the ROM addresses of the breakpoints in order
+func (d *Debugger) Breakpoints() []int {
+	addrs := make([]int, 0, len(d.breakpoints))
+	for addr := range d.breakpoints {
+		addrs = append(addrs, addr)
+	}
+	sort.Ints(addrs)
+	return addrs
+}
+
+// Step executes a single instruction
+func (d *Debugger) Step() (Stop, error) {
+	return d.run(1, func(int) bool {
+		return true
+	})
+}
+
+// StepVM executes instructions up to the start of the next VM command,
+// at most maxCycles
+func (d *Debugger) StepVM(maxCycles int) (Stop, error) {
+	if d.program.Source == nil {
+		return Stepped, fmt.Errorf("no VM source to step")
+	}
+	return d.run(maxCycles, func(pc int) bool {
+		return d.program.Source.IsCommand(pc)
+	})
+}
+
+// Continue executes instructions up to the next breakpoint, at most
+// maxCycles
+func (d *Debugger) Continue(maxCycles int) (Stop, error) {
+	return d.run(maxCycles, nil)
+}
+
+// run executes up to maxCycles instructions until the program halts,
+// reaches a breakpoint or stepped returns true
+func (d *Debugger) run(maxCycles int, stepped func(pc int) bool) (Stop, error) {
+	for i := 0; i < maxCycles; i++ {
+		if d.Machine.Halted() {
+			return Halted, nil
+		}
+		err := d.Machine.Step()
+		if err != nil {
+			return Stepped, err
+		}
+		pc := int(d.Machine.PC)
+		switch {
+		case d.Machine.Halted():
+			return Halted, nil
+		case d.breakpoints[pc]:
+			return Breakpoint, nil
+		case stepped != nil && stepped(pc):
+			return Stepped, nil
+		}
+	}
+	return Limit, nil
+}
+
+// Location returns the ROM address relative to the closest label at or
+// before it, e.g. Main.main+3. Of several labels on the address the
+// first by name is used, a function before its return labels.
+func (d *Debugger) Location(addr int) string {
+	i := sort.Search(len(d.labels), func(i int) bool {
+		return d.labels[i].addr > addr
+	})
+	if i == 0 {
+		return ""
+	}
+	for i > 1 && d.labels[i-2].addr == d.labels[i-1].addr {
+		i--
+	}
+	l := d.labels[i-1]
+	if l.addr == addr {
+		return l.name
+	}
+	return fmt.Sprintf("%s+%d", l.name, addr-l.addr)
+}
+
+// Instruction returns the assembly of the instruction at the ROM address
+func (d *Debugger) Instruction(addr int) string {
+	if addr < 0 || addr >= cpu.MemorySize {
+		return ""
+	}
+	cmd, err := asm.Decode(d.Machine.ROM[addr])
+	if err != nil {
+		return fmt.Sprintf("%016b", d.Machine.ROM[addr])
+	}
+	return cmd.String()
+}
+
+// Source returns the VM command of the instruction at the ROM address
+func (d *Debugger) Source(addr int) (translator.Source, bool) {
+	if d.program.Source == nil {
+		return translator.Source{}, false
+	}
+	return d.program.Source.Lookup(addr)
+}
+
+// ram returns a copy of RAM[from:to] or nil for an invalid range
+func (d *Debugger) ram(from, to int) []int16 {
+	if from < 0 || to > cpu.MemorySize || from > to {
+		return nil
+	}
+	words := make([]int16, to-from)
+	copy(words, d.Machine.RAM[from:to])
+	return words
+}
+
+// Stack returns the stack from its base 256 up to SP
+func (d *Debugger) Stack() []int16 {
+	return d.ram(256, int(d.Machine.RAM[0]))
+}
+
+// Frame is the frame of the current function
+type Frame struct {
+	Function string
+
+	LCL, ARG, THIS, THAT int16
+
+	Args   []int16
+	Locals []int16
+	// Stack holds the working stack of the function above its locals
+	Stack      []int16
+	ReturnAddr int16
+}
+
+// Frame returns the frame of the function at the PC. Without VM source
+// the number of local variables is unknown, they are part of the stack.
+func (d *Debugger) Frame() Frame {
+	ram := &d.Machine.RAM
+	f := Frame{
+		LCL:  ram[1],
+		ARG:  ram[2],
+		THIS: ram[3],
+		THAT: ram[4],
+	}
+	if src, ok := d.Source(int(d.Machine.PC)); ok {
+		f.Function = src.Function
+	}
+	lcl, sp := int(f.LCL), int(ram[0])
+	if lcl < 5 || lcl > sp {
+		return f
+	}
+	f.ReturnAddr = ram[lcl-5]
+	f.Args = d.ram(int(f.ARG), lcl-5)
+	locals := lcl + d.locals[f.Function]
+	if locals > sp {
+		locals = sp
+	}
+	f.Locals = d.ram(lcl, locals)
+	f.Stack = d.ram(locals, sp)
+	return f
+}
+
+// Statics returns the static variables of the program
+func (d *Debugger) Statics() []Static {
+	return d.program.Statics
+}
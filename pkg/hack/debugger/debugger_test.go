@@ -0,0 +1,240 @@
+package debugger_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	asm "github.com/wongak/nand2tetris/pkg/hack/assembly/language"
+	"github.com/wongak/nand2tetris/pkg/hack/debugger"
+	"github.com/wongak/nand2tetris/pkg/hack/vm/translator"
+)
+
+func translated(t *testing.T) *debugger.Debugger {
+	fileNames, err := translator.DirFiles(filepath.Join("..", "vm", "translator", "testdata", "StaticsTest"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := translator.New()
+	err = tr.Bootstrap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fileName := range fileNames {
+		err = tr.TranslateFile(fileName)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	p, err := debugger.Translated(tr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := debugger.New(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func expectStop(t *testing.T, expect, stop debugger.Stop, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stop != expect {
+		t.Fatalf("expect stop %s, got %s", expect, stop)
+	}
+}
+
+func TestDebugVM(t *testing.T) {
+	d := translated(t)
+
+	addr, err := d.Resolve("Sys.vm:5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Break(addr)
+	stop, err := d.Continue(100000)
+	expectStop(t, debugger.Breakpoint, stop, err)
+	if int(d.Machine.PC) != addr {
+		t.Fatalf("expect PC %d, got %d", addr, d.Machine.PC)
+	}
+	src, _ := d.Source(addr)
+	if src.Code != "call Class1.set 2" || src.Function != "Sys.init" {
+		t.Errorf("expect call Class1.set 2 in Sys.init, got %+v", src)
+	}
+	if !reflect.DeepEqual(d.Frame().Stack, []int16{6, 8}) {
+		t.Errorf("expect arguments 6 and 8 on the stack, got %v", d.Frame().Stack)
+	}
+
+	// into the called function
+	stop, err = d.StepVM(100000)
+	expectStop(t, debugger.Stepped, stop, err)
+	src, _ = d.Source(int(d.Machine.PC))
+	if src.Code != "push argument 0" || src.Function != "Class1.set" || src.Line != 3 {
+		t.Errorf("expect push argument 0 on line 3 of Class1.set, got %+v", src)
+	}
+	if d.Location(int(d.Machine.PC)) != "Class1.set" {
+		t.Errorf("expect location Class1.set, got %s", d.Location(int(d.Machine.PC)))
+	}
+	f := d.Frame()
+	if f.Function != "Class1.set" || !reflect.DeepEqual(f.Args, []int16{6, 8}) || len(f.Locals) != 0 || len(f.Stack) != 0 {
+		t.Errorf("expect frame of Class1.set with arguments 6 and 8, got %+v", f)
+	}
+	if int(f.ReturnAddr) != d.Program().Labels["Class1.set$ret.0"] {
+		t.Errorf("expect return address %d, got %d", d.Program().Labels["Class1.set$ret.0"], f.ReturnAddr)
+	}
+
+	stop, err = d.Step()
+	expectStop(t, debugger.Stepped, stop, err)
+	if d.Location(int(d.Machine.PC)) != "Class1.set+1" {
+		t.Errorf("expect one instruction into Class1.set, got %s", d.Location(int(d.Machine.PC)))
+	}
+
+	d.Clear(addr)
+	stop, err = d.Continue(100000)
+	expectStop(t, debugger.Halted, stop, err)
+	if !reflect.DeepEqual(d.Frame().Stack, []int16{-2, 8}) {
+		t.Errorf("expect results -2 and 8 on the stack, got %v", d.Frame().Stack)
+	}
+	expect := map[string]int16{"Class1.0": 6, "Class1.1": 8, "Class2.0": 23, "Class2.1": 15}
+	statics := d.Statics()
+	if len(statics) != len(expect) {
+		t.Fatalf("expect %d statics, got %v", len(expect), statics)
+	}
+	for _, s := range statics {
+		if d.Machine.RAM[s.Addr] != expect[s.Name] {
+			t.Errorf("expect %s = %d, got %d", s.Name, expect[s.Name], d.Machine.RAM[s.Addr])
+		}
+	}
+
+	d.Reset()
+	if d.Machine.PC != 0 || d.Machine.RAM[0] != 0 {
+		t.Error("expect reset machine")
+	}
+}
+
+func TestResolve(t *testing.T) {
+	d := translated(t)
+	for _, c := range []struct {
+		location string
+		err      string
+	}{
+		{"Class2.get", ""},
+		{"Sys.init$WHILE", ""},
+		{"Class1.vm:12", ""},
+		{"12", ""},
+		{"Sys.vm:1", "no VM command on Sys.vm:1"},
+		{"99999", "address 99999 out of program"},
+		{"Foo.bar", "unknown location Foo.bar"},
+	} {
+		_, err := d.Resolve(c.location)
+		if c.err == "" && err != nil {
+			t.Errorf("%s: unexpected error: %v", c.location, err)
+		}
+		if c.err != "" && (err == nil || err.Error() != c.err) {
+			t.Errorf("%s: expect error %s, got %v", c.location, c.err, err)
+		}
+	}
+}
+
+func TestDebugAsm(t *testing.T) {
+	p := asm.NewParser(strings.NewReader(`
+	@3
+	D=A
+(LOOP)
+	@R0
+	M=M+1
+	D=D-1
+	@LOOP
+	D;JGT
+(END)
+	@END
+	0;JMP
+`))
+	err := p.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	prog, err := debugger.Assembled(p.Tree())
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := debugger.New(prog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = d.StepVM(10); err == nil {
+		t.Error("expect error stepping VM commands without source")
+	}
+
+	addr, err := d.Resolve("LOOP")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Break(addr + 2)
+	if d.Location(addr+2) != "LOOP+2" || d.Instruction(addr+2) != "D=D-1" {
+		t.Errorf("expect D=D-1 at LOOP+2, got %s at %s", d.Instruction(addr+2), d.Location(addr+2))
+	}
+	for i := 1; i <= 3; i++ {
+		stop, err := d.Continue(100)
+		expectStop(t, debugger.Breakpoint, stop, err)
+		if d.Machine.RAM[0] != int16(i) {
+			t.Errorf("expect R0 %d, got %d", i, d.Machine.RAM[0])
+		}
+	}
+	stop, err := d.Continue(100)
+	expectStop(t, debugger.Halted, stop, err)
+	if !reflect.DeepEqual(d.Breakpoints(), []int{addr + 2}) {
+		t.Errorf("expect breakpoint %d, got %v", addr+2, d.Breakpoints())
+	}
+
+	d.Reset()
+	stop, err = d.Continue(3)
+	expectStop(t, debugger.Limit, stop, err)
+}
+
+func TestFrameLocals(t *testing.T) {
+	dir := t.TempDir()
+	for name, src := range map[string]string{
+		"Sys.vm":  "function Sys.init 0\npush constant 7\ncall Main.f 1\nlabel END\ngoto END\n",
+		"Main.vm": "function Main.f 2\npush constant 5\npop local 1\npush argument 0\nreturn\n",
+	} {
+		err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	fileNames, err := translator.DirFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := translator.New()
+	err = tr.Build(fileNames, translator.Options{Check: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := debugger.Translated(tr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := debugger.New(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addr, err := d.Resolve("Main.vm:4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Break(addr)
+	stop, err := d.Continue(100000)
+	expectStop(t, debugger.Breakpoint, stop, err)
+	f := d.Frame()
+	if f.Function != "Main.f" || !reflect.DeepEqual(f.Args, []int16{7}) || !reflect.DeepEqual(f.Locals, []int16{0, 5}) || len(f.Stack) != 0 {
+		t.Errorf("expect frame of Main.f with 2 locals, got %+v", f)
+	}
+}
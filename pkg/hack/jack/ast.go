@@ -0,0 +1,194 @@
+package jack
+
+// Node is a node of the abstract syntax tree
+type Node interface {
+	Pos() Pos
+}
+
+type node struct {
+	pos Pos
+}
+
+// Pos returns the position of the node in the source
+func (n node) Pos() Pos {
+	return n.pos
+}
+
+type (
+	// Class is the root of a Jack file
+	Class struct {
+		node
+		Name        string
+		Vars        []*ClassVarDec
+		Subroutines []*Subroutine
+	}
+
+	// ClassVarDec declares static or field variables
+	ClassVarDec struct {
+		node
+		Kind  Token // STATIC or FIELD
+		Type  Type
+		Names []string
+	}
+
+	// Type is a variable or return type. Name holds the class name
+	// for class types.
+	Type struct {
+		Token Token // INTTYPE, CHAR, BOOLEAN, VOID or IDENT
+		Name  string
+	}
+
+	// Subroutine is a constructor, function or method declaration
+	Subroutine struct {
+		node
+		Kind       Token // CONSTRUCTOR, FUNCTION or METHOD
+		ReturnType Type
+		Name       string
+		Params     []*Param
+		Vars       []*VarDec
+		Body       []Statement
+	}
+
+	// Param is a subroutine parameter
+	Param struct {
+		node
+		Type Type
+		Name string
+	}
+
+	// VarDec declares local variables
+	VarDec struct {
+		node
+		Type  Type
+		Names []string
+	}
+)
+
+func (t Type) String() string {
+	if t.Token == IDENT {
+		return t.Name
+	}
+	return t.Token.String()
+}
+
+// Statement is a Jack statement
+type Statement interface {
+	Node
+	statement()
+}
+
+type (
+	// LetStatement assigns Value to Name or Name[Index]
+	LetStatement struct {
+		node
+		Name  string
+		Index Expression
+		Value Expression
+	}
+
+	// IfStatement executes Then if Cond is true, otherwise Else
+	IfStatement struct {
+		node
+		Cond Expression
+		Then []Statement
+		Else []Statement
+	}
+
+	// WhileStatement executes Body while Cond is true
+	WhileStatement struct {
+		node
+		Cond Expression
+		Body []Statement
+	}
+
+	// DoStatement calls a subroutine and discards the result
+	DoStatement struct {
+		node
+		Call *CallExpr
+	}
+
+	// ReturnStatement returns from a subroutine. Value may be nil.
+	ReturnStatement struct {
+		node
+		Value Expression
+	}
+)
+
+func (*LetStatement) statement()    {}
+func (*IfStatement) statement()     {}
+func (*WhileStatement) statement()  {}
+func (*DoStatement) statement()     {}
+func (*ReturnStatement) statement() {}
+
+// Expression is a Jack expression
+type Expression interface {
+	Node
+	expression()
+}
+
+type (
+	// BinaryExpr applies Op to Left and Right. Jack has no operator
+	// precedence, expressions are evaluated left to right.
+	BinaryExpr struct {
+		node
+		Op    Token
+		Left  Expression
+		Right Expression
+	}
+
+	// UnaryExpr applies MINUS or TILDE to Operand
+	UnaryExpr struct {
+		node
+		Op      Token
+		Operand Expression
+	}
+
+	// IntegerConst is an integer constant 0..32767
+	IntegerConst struct {
+		node
+		Value int
+	}
+
+	// StringConst is a string constant
+	StringConst struct {
+		node
+		Value string
+	}
+
+	// KeywordConst is one of true, false, null or this
+	KeywordConst struct {
+		node
+		Keyword Token
+	}
+
+	// VarRef references a variable
+	VarRef struct {
+		node
+		Name string
+	}
+
+	// IndexExpr accesses an array element Name[Index]
+	IndexExpr struct {
+		node
+		Name  string
+		Index Expression
+	}
+
+	// CallExpr calls a subroutine. Receiver is empty for calls of
+	// methods of the current object, otherwise a class or variable name.
+	CallExpr struct {
+		node
+		Receiver string
+		Name     string
+		Args     []Expression
+	}
+)
+
+func (*BinaryExpr) expression()   {}
+func (*UnaryExpr) expression()    {}
+func (*IntegerConst) expression() {}
+func (*StringConst) expression()  {}
+func (*KeywordConst) expression() {}
+func (*VarRef) expression()       {}
+func (*IndexExpr) expression()    {}
+func (*CallExpr) expression()     {}
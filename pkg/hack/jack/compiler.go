@@ -0,0 +1,418 @@
+package jack
+
+import (
+	"fmt"
+
+	vm "github.com/wongak/nand2tetris/pkg/hack/vm/language"
+)
+
+// Compiler generates VM commands for a Jack class
+type Compiler struct {
+	class   *Class
+	file    *vm.File
+	symbols *SymbolTable
+
+	subroutine *Subroutine
+	function   *vm.Function
+	ifIndex    int
+	whileIndex int
+	// pos is the position of the statement or expression compiled
+	pos vm.Pos
+
+	tree []vm.Command
+}
+
+// NewCompiler creates a compiler for the parsed class
+func NewCompiler(class *Class) *Compiler {
+	return &Compiler{
+		class:   class,
+		symbols: NewSymbolTable(),
+
+		tree: make([]vm.Command, 0),
+	}
+}
+
+// Run compiles the class and registers the class file in the VM symbol
+// table, so the generated commands can be translated right away.
+func (c *Compiler) Run(table *vm.SymbolTable) error {
+	c.file = vm.NewFile(c.class.Name)
+	_, err := table.RegisterFile(c.class.Name)
+	if err != nil {
+		return err
+	}
+
+	for _, v := range c.class.Vars {
+		kind := KindField
+		if v.Kind == STATIC {
+			kind = KindStatic
+		}
+		for _, name := range v.Names {
+			if !c.symbols.Define(name, v.Type, kind) {
+				return c.errorf(v, "variable %s already declared", name)
+			}
+		}
+	}
+	for _, s := range c.class.Subroutines {
+		err := c.compileSubroutine(s)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Tree returns the generated commands
+func (c *Compiler) Tree() []vm.Command {
+	return c.tree
+}
+
+func (c *Compiler) errorf(n Node, format string, args ...interface{}) error {
+	return &Error{Pos: n.Pos(), Msg: fmt.Sprintf(format, args...)}
+}
+
+// setPos sets the position of the generated commands to the node and
+// returns the previous position
+func (c *Compiler) setPos(n Node) vm.Pos {
+	prev := c.pos
+	pos := n.Pos()
+	c.pos = vm.Pos{File: pos.File, Line: pos.Line, Col: pos.Col}
+	return prev
+}
+
+func (c *Compiler) emit(cmd vm.Command) {
+	c.tree = append(c.tree, vm.At(c.pos, cmd))
+}
+
+func (c *Compiler) push(seg vm.Token, index int) {
+	c.emit(vm.NewMemoryAccess(vm.PUSH, seg, index, c.file))
+}
+
+func (c *Compiler) pop(seg vm.Token, index int) {
+	c.emit(vm.NewMemoryAccess(vm.POP, seg, index, c.file))
+}
+
+func (c *Compiler) arithmetic(cmd vm.Token) {
+	c.emit(vm.NewArithmetic(cmd, c.file))
+}
+
+func (c *Compiler) call(name string, numArgs int) {
+	c.emit(vm.NewCall(name, numArgs))
+}
+
+func (c *Compiler) label(name string) {
+	c.emit(vm.NewLabel(name, c.function))
+}
+
+func (c *Compiler) compileSubroutine(s *Subroutine) error {
+	c.setPos(s)
+	c.subroutine = s
+	c.ifIndex = 0
+	c.whileIndex = 0
+	c.symbols.StartSubroutine()
+
+	if s.Kind == METHOD {
+		// the object is passed as argument 0
+		c.symbols.Define("this", Type{Token: IDENT, Name: c.class.Name}, KindArg)
+	}
+	for _, p := range s.Params {
+		if !c.symbols.Define(p.Name, p.Type, KindArg) {
+			return c.errorf(p, "parameter %s already declared", p.Name)
+		}
+	}
+	for _, v := range s.Vars {
+		for _, name := range v.Names {
+			if !c.symbols.Define(name, v.Type, KindVar) {
+				return c.errorf(v, "variable %s already declared", name)
+			}
+		}
+	}
+
+	c.function = vm.NewFunction(c.class.Name+"."+s.Name, c.symbols.Count(KindVar))
+	c.emit(c.function)
+	switch s.Kind {
+	case CONSTRUCTOR:
+		c.push(vm.CONSTANT, c.symbols.Count(KindField))
+		c.call("Memory.alloc", 1)
+		c.pop(vm.POINTER, 0)
+	case METHOD:
+		c.push(vm.ARG, 0)
+		c.pop(vm.POINTER, 0)
+	}
+	return c.compileStatements(s.Body)
+}
+
+func (c *Compiler) compileStatements(stmts []Statement) error {
+	for _, stmt := range stmts {
+		prev := c.setPos(stmt)
+		var err error
+		switch s := stmt.(type) {
+		case *LetStatement:
+			err = c.compileLet(s)
+		case *IfStatement:
+			err = c.compileIf(s)
+		case *WhileStatement:
+			err = c.compileWhile(s)
+		case *DoStatement:
+			err = c.compileCall(s.Call)
+			c.pop(vm.TEMP, 0)
+		case *ReturnStatement:
+			err = c.compileReturn(s)
+		default:
+			err = c.errorf(stmt, "unsupported statement %T", stmt)
+		}
+		c.pos = prev
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Compiler) lookup(n Node, name string) (*Symbol, error) {
+	sym, ok := c.symbols.Lookup(name)
+	if !ok {
+		return nil, c.errorf(n, "undefined variable %s", name)
+	}
+	if sym.Kind == KindField && c.subroutine.Kind == FUNCTION {
+		return nil, c.errorf(n, "field %s used in function %s", name, c.subroutine.Name)
+	}
+	return sym, nil
+}
+
+func (c *Compiler) compileLet(s *LetStatement) error {
+	sym, err := c.lookup(s, s.Name)
+	if err != nil {
+		return err
+	}
+	if s.Index == nil {
+		err = c.compileExpression(s.Value)
+		if err != nil {
+			return err
+		}
+		c.pop(sym.Kind.Segment(), sym.Index)
+		return nil
+	}
+
+	// the value is evaluated before THAT is set, as it may access arrays
+	c.push(sym.Kind.Segment(), sym.Index)
+	err = c.compileExpression(s.Index)
+	if err != nil {
+		return err
+	}
+	c.arithmetic(vm.ADD)
+	err = c.compileExpression(s.Value)
+	if err != nil {
+		return err
+	}
+	c.pop(vm.TEMP, 0)
+	c.pop(vm.POINTER, 1)
+	c.push(vm.TEMP, 0)
+	c.pop(vm.THAT, 0)
+	return nil
+}
+
+func (c *Compiler) compileIf(s *IfStatement) error {
+	index := c.ifIndex
+	c.ifIndex++
+	labelFalse := fmt.Sprintf("IF_FALSE%d", index)
+	labelEnd := fmt.Sprintf("IF_END%d", index)
+
+	err := c.compileExpression(s.Cond)
+	if err != nil {
+		return err
+	}
+	c.arithmetic(vm.NOT)
+	c.emit(vm.NewIfGoto(labelFalse, c.function))
+	err = c.compileStatements(s.Then)
+	if err != nil {
+		return err
+	}
+	if s.Else == nil {
+		c.label(labelFalse)
+		return nil
+	}
+	c.emit(vm.NewGoto(labelEnd, c.function))
+	c.label(labelFalse)
+	err = c.compileStatements(s.Else)
+	if err != nil {
+		return err
+	}
+	c.label(labelEnd)
+	return nil
+}
+
+func (c *Compiler) compileWhile(s *WhileStatement) error {
+	index := c.whileIndex
+	c.whileIndex++
+	labelExp := fmt.Sprintf("WHILE_EXP%d", index)
+	labelEnd := fmt.Sprintf("WHILE_END%d", index)
+
+	c.label(labelExp)
+	err := c.compileExpression(s.Cond)
+	if err != nil {
+		return err
+	}
+	c.arithmetic(vm.NOT)
+	c.emit(vm.NewIfGoto(labelEnd, c.function))
+	err = c.compileStatements(s.Body)
+	if err != nil {
+		return err
+	}
+	c.emit(vm.NewGoto(labelExp, c.function))
+	c.label(labelEnd)
+	return nil
+}
+
+func (c *Compiler) compileReturn(s *ReturnStatement) error {
+	if s.Value == nil {
+		if c.subroutine.ReturnType.Token != VOID {
+			return c.errorf(s, "missing return value in %s", c.subroutine.Name)
+		}
+		c.push(vm.CONSTANT, 0)
+	} else {
+		if c.subroutine.ReturnType.Token == VOID {
+			return c.errorf(s, "return value in void %s", c.subroutine.Name)
+		}
+		err := c.compileExpression(s.Value)
+		if err != nil {
+			return err
+		}
+	}
+	c.emit(vm.NewReturn(c.function))
+	return nil
+}
+
+var binaryOps = map[Token]vm.Token{
+	PLUS:      vm.ADD,
+	MINUS:     vm.SUB,
+	AMPERSAND: vm.AND,
+	PIPE:      vm.OR,
+	LT:        vm.LT,
+	GT:        vm.GT,
+	EQUALS:    vm.EQ,
+}
+
+func (c *Compiler) compileExpression(expr Expression) error {
+	prev := c.setPos(expr)
+	defer func() {
+		c.pos = prev
+	}()
+	switch e := expr.(type) {
+	case *IntegerConst:
+		c.push(vm.CONSTANT, e.Value)
+	case *StringConst:
+		c.push(vm.CONSTANT, len(e.Value))
+		c.call("String.new", 1)
+		for _, ch := range []byte(e.Value) {
+			c.push(vm.CONSTANT, int(ch))
+			c.call("String.appendChar", 2)
+		}
+	case *KeywordConst:
+		switch e.Keyword {
+		case TRUE:
+			c.push(vm.CONSTANT, 0)
+			c.arithmetic(vm.NOT)
+		case FALSE, NULL:
+			c.push(vm.CONSTANT, 0)
+		case THIS:
+			if c.subroutine.Kind == FUNCTION {
+				return c.errorf(e, "this used in function %s", c.subroutine.Name)
+			}
+			c.push(vm.POINTER, 0)
+		}
+	case *VarRef:
+		sym, err := c.lookup(e, e.Name)
+		if err != nil {
+			return err
+		}
+		c.push(sym.Kind.Segment(), sym.Index)
+	case *IndexExpr:
+		sym, err := c.lookup(e, e.Name)
+		if err != nil {
+			return err
+		}
+		c.push(sym.Kind.Segment(), sym.Index)
+		err = c.compileExpression(e.Index)
+		if err != nil {
+			return err
+		}
+		c.arithmetic(vm.ADD)
+		c.pop(vm.POINTER, 1)
+		c.push(vm.THAT, 0)
+	case *UnaryExpr:
+		err := c.compileExpression(e.Operand)
+		if err != nil {
+			return err
+		}
+		if e.Op == MINUS {
+			c.arithmetic(vm.NEG)
+		} else {
+			c.arithmetic(vm.NOT)
+		}
+	case *BinaryExpr:
+		err := c.compileExpression(e.Left)
+		if err != nil {
+			return err
+		}
+		err = c.compileExpression(e.Right)
+		if err != nil {
+			return err
+		}
+		switch e.Op {
+		case ASTERISK:
+			c.call("Math.multiply", 2)
+		case SLASH:
+			c.call("Math.divide", 2)
+		default:
+			c.arithmetic(binaryOps[e.Op])
+		}
+	case *CallExpr:
+		return c.compileCall(e)
+	default:
+		return c.errorf(expr, "unsupported expression %T", expr)
+	}
+	return nil
+}
+
+// compileCall pushes the object for method calls and the arguments
+// and calls the subroutine
+func (c *Compiler) compileCall(call *CallExpr) error {
+	numArgs := len(call.Args)
+	var name string
+	switch {
+	case call.Receiver == "":
+		// method of the current object
+		if c.subroutine.Kind == FUNCTION {
+			return c.errorf(call, "method %s called from function %s", call.Name, c.subroutine.Name)
+		}
+		c.push(vm.POINTER, 0)
+		numArgs++
+		name = c.class.Name + "." + call.Name
+	default:
+		sym, ok := c.symbols.Lookup(call.Receiver)
+		if !ok {
+			// function or constructor of a class
+			name = call.Receiver + "." + call.Name
+			break
+		}
+		if sym.Type.Token != IDENT {
+			return c.errorf(call, "method %s called on %s of type %s", call.Name, call.Receiver, sym.Type)
+		}
+		sym, err := c.lookup(call, call.Receiver)
+		if err != nil {
+			return err
+		}
+		c.push(sym.Kind.Segment(), sym.Index)
+		numArgs++
+		name = sym.Type.Name + "." + call.Name
+	}
+
+	for _, arg := range call.Args {
+		err := c.compileExpression(arg)
+		if err != nil {
+			return err
+		}
+	}
+	c.call(name, numArgs)
+	return nil
+}
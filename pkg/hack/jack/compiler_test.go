@@ -0,0 +1,273 @@
+package jack_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wongak/nand2tetris/pkg/hack/jack"
+	"github.com/wongak/nand2tetris/pkg/hack/vm/emulator"
+	vm "github.com/wongak/nand2tetris/pkg/hack/vm/language"
+)
+
+func compile(t *testing.T, table *vm.SymbolTable, src string) []vm.Command {
+	p := jack.NewParser(strings.NewReader(src), "Test.jack")
+	err := p.Run()
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+	c := jack.NewCompiler(p.Tree())
+	err = c.Run(table)
+	if err != nil {
+		t.Fatalf("unexpected compile error: %v", err)
+	}
+	return c.Tree()
+}
+
+func TestCompileFunction(t *testing.T) {
+	cmds := compile(t, vm.NewSymbolTable(), `
+class Main {
+	static int count;
+
+	function int add(int a, int b) {
+		var int sum;
+		let sum = a + b;
+		if (sum > 10) {
+			let count = count + 1;
+		}
+		return sum;
+	}
+}`)
+	expect := []string{
+		"FUNCTION Main.add 1",
+		"PUSH ARG 0",
+		"PUSH ARG 1",
+		"ADD",
+		"POP LCL 0",
+		"PUSH LCL 0",
+		"PUSH CONSTANT 10",
+		"GT",
+		"NOT",
+		"IFGOTO IF_FALSE0",
+		"PUSH STATIC 0",
+		"PUSH CONSTANT 1",
+		"ADD",
+		"POP STATIC 0",
+		"LABEL IF_FALSE0",
+		"PUSH LCL 0",
+		"RETURN",
+	}
+	if len(cmds) != len(expect) {
+		t.Fatalf("expect %d commands, got %d: %v", len(expect), len(cmds), cmds)
+	}
+	for i, e := range expect {
+		if cmds[i].String() != e {
+			t.Errorf("expect command %d %s, got %s", i, e, cmds[i])
+		}
+	}
+}
+
+// os is a minimal VM implementation of the OS functions used by the
+// test programs
+const os = `
+function Memory.alloc 0
+	push static 0
+	push constant 0
+	eq
+	not
+	if-goto ALLOC
+	push constant 2048
+	pop static 0
+label ALLOC
+	push static 0
+	push static 0
+	push argument 0
+	add
+	pop static 0
+	return
+function Array.new 0
+	push argument 0
+	call Memory.alloc 1
+	return
+function Math.multiply 1
+label LOOP
+	push argument 1
+	push constant 0
+	eq
+	if-goto END
+	push local 0
+	push argument 0
+	add
+	pop local 0
+	push argument 1
+	push constant 1
+	sub
+	pop argument 1
+	goto LOOP
+label END
+	push local 0
+	return
+`
+
+const program = `
+class Main {
+	static int result;
+
+	function void main() {
+		var Array a;
+		var Point p;
+		var int i, sum;
+		let a = Array.new(5);
+		let i = 0;
+		while (i < 5) {
+			let a[i] = i * i;
+			let i = i + 1;
+		}
+		let a[a[1]] = a[2] + a[3]; // a[1] = 4 + 9 = 13
+		let p = Point.new(3, 4);
+		let sum = 0;
+		let i = 0;
+		while (i < 5) {
+			let sum = sum + a[i];
+			let i = i + 1;
+		}
+		let result = sum + p.dist();
+		if (~(result = 0) & true) {
+			let result = -result;
+		} else {
+			let result = 0;
+		}
+		return;
+	}
+}
+`
+
+const point = `
+class Point {
+	field int x, y;
+
+	constructor Point new(int ax, int ay) {
+		let x = ax;
+		let y = ay;
+		return this;
+	}
+
+	method int dist() {
+		return x + y;
+	}
+}
+`
+
+func TestCompileAndRun(t *testing.T) {
+	table := vm.NewSymbolTable()
+	cmds := compile(t, table, program)
+	cmds = append(cmds, compile(t, table, point)...)
+	p := vm.NewParser(strings.NewReader(os))
+	err := p.Run(table, "OS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmds = append(cmds, p.Tree()...)
+
+	prog, err := emulator.NewProgram(cmds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := emulator.New(prog)
+	e.RAM[emulator.SP] = emulator.StackBase
+	e.PC = len(cmds)
+	// call Main.main with the end of the program as return address
+	for i, cmd := range cmds {
+		if f, ok := cmd.(*vm.Function); ok && f.Name() == "Main.main" {
+			e.RAM[emulator.SP] = emulator.StackBase + 5
+			e.RAM[emulator.LCL] = emulator.StackBase + 5
+			e.RAM[emulator.StackBase] = int16(len(cmds))
+			e.PC = i
+		}
+	}
+	_, err = e.Run(100000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !e.Halted() {
+		t.Fatal("expect program to halt")
+	}
+	// 0 + 13 + 4 + 9 + 16 = 42, plus 3 + 4
+	if e.Static("Main", 0) != -49 {
+		t.Errorf("expect result -49, got %d", e.Static("Main", 0))
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	cases := []struct {
+		src    string
+		expect string
+	}{
+		{
+			src:    "class Main {\n  function void main() {\n    let x = 1;\n    return;\n  }\n}",
+			expect: "Test.jack:3:5: undefined variable x",
+		},
+		{
+			src:    "class Main {\n  field int x;\n  function int f() {\n    return x;\n  }\n}",
+			expect: "Test.jack:4:12: field x used in function f",
+		},
+		{
+			src:    "class Main {\n  function void main() {\n    do run();\n    return;\n  }\n}",
+			expect: "Test.jack:3:8: method run called from function main",
+		},
+		{
+			src:    "class Main {\n  function int f() {\n    return;\n  }\n}",
+			expect: "Test.jack:3:5: missing return value in f",
+		},
+	}
+	for _, c := range cases {
+		p := jack.NewParser(strings.NewReader(c.src), "Test.jack")
+		err := p.Run()
+		if err != nil {
+			t.Fatalf("unexpected parse error: %v", err)
+		}
+		err = jack.NewCompiler(p.Tree()).Run(vm.NewSymbolTable())
+		if err == nil {
+			t.Errorf("expect error %s", c.expect)
+			continue
+		}
+		if err.Error() != c.expect {
+			t.Errorf("expect error\n%s, got\n%s", c.expect, err)
+		}
+	}
+}
+
+func TestCompilePos(t *testing.T) {
+	cmds := compile(t, vm.NewSymbolTable(), `class Main {
+	function int max(int a, int b) {
+		if (a > b) {
+			return a;
+		}
+		return b;
+	}
+}`)
+	expect := []struct {
+		code string
+		line int
+	}{
+		{"function Main.max 0", 2},
+		{"push argument 0", 3},
+		{"push argument 1", 3},
+		{"gt", 3},
+		{"not", 3},
+		{"if-goto IF_FALSE0", 3},
+		{"push argument 0", 4},
+		{"return", 4},
+		{"label IF_FALSE0", 3},
+		{"push argument 1", 6},
+		{"return", 6},
+	}
+	if len(cmds) != len(expect) {
+		t.Fatalf("expect %d commands, got %d: %v", len(expect), len(cmds), cmds)
+	}
+	for i, e := range expect {
+		pos := cmds[i].Pos()
+		if cmds[i].Code() != e.code || pos.File != "Test.jack" || pos.Line != e.line {
+			t.Errorf("expect %s at Test.jack:%d, got %s at %s", e.code, e.line, cmds[i].Code(), pos)
+		}
+	}
+}
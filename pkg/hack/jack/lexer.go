@@ -0,0 +1,429 @@
+package jack
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// Token is a lexical token
+type Token int
+
+const (
+	ILLEGAL Token = iota
+	EOF
+	WS
+	COMMENT
+
+	IDENT
+	INT
+	STRING
+
+	// keywords
+	CLASS
+	CONSTRUCTOR
+	FUNCTION
+	METHOD
+	FIELD
+	STATIC
+	VAR
+	INTTYPE
+	CHAR
+	BOOLEAN
+	VOID
+	TRUE
+	FALSE
+	NULL
+	THIS
+	LET
+	DO
+	IF
+	ELSE
+	WHILE
+	RETURN
+
+	// symbols
+	LBRACE
+	RBRACE
+	LPAREN
+	RPAREN
+	LBRACKET
+	RBRACKET
+	DOT
+	COMMA
+	SEMICOLON
+	PLUS
+	MINUS
+	ASTERISK
+	SLASH
+	AMPERSAND
+	PIPE
+	LT
+	GT
+	EQUALS
+	TILDE
+)
+
+var tokens = map[Token]string{
+	ILLEGAL: "ILLEGAL",
+	EOF:     "EOF",
+	WS:      "WS",
+	COMMENT: "COMMENT",
+
+	IDENT:  "IDENT",
+	INT:    "INT",
+	STRING: "STRING",
+
+	CLASS:       "class",
+	CONSTRUCTOR: "constructor",
+	FUNCTION:    "function",
+	METHOD:      "method",
+	FIELD:       "field",
+	STATIC:      "static",
+	VAR:         "var",
+	INTTYPE:     "int",
+	CHAR:        "char",
+	BOOLEAN:     "boolean",
+	VOID:        "void",
+	TRUE:        "true",
+	FALSE:       "false",
+	NULL:        "null",
+	THIS:        "this",
+	LET:         "let",
+	DO:          "do",
+	IF:          "if",
+	ELSE:        "else",
+	WHILE:       "while",
+	RETURN:      "return",
+
+	LBRACE:    "{",
+	RBRACE:    "}",
+	LPAREN:    "(",
+	RPAREN:    ")",
+	LBRACKET:  "[",
+	RBRACKET:  "]",
+	DOT:       ".",
+	COMMA:     ",",
+	SEMICOLON: ";",
+	PLUS:      "+",
+	MINUS:     "-",
+	ASTERISK:  "*",
+	SLASH:     "/",
+	AMPERSAND: "&",
+	PIPE:      "|",
+	LT:        "<",
+	GT:        ">",
+	EQUALS:    "=",
+	TILDE:     "~",
+}
+
+var keywords map[string]Token
+var symbols map[rune]Token
+
+func init() {
+	keywords = make(map[string]Token)
+	for tok := CLASS; tok <= RETURN; tok++ {
+		keywords[tokens[tok]] = tok
+	}
+	symbols = make(map[rune]Token)
+	for tok := LBRACE; tok <= TILDE; tok++ {
+		symbols[rune(tokens[tok][0])] = tok
+	}
+}
+
+func (t Token) String() string {
+	if str, ok := tokens[t]; ok {
+		return str
+	}
+	return "unknown token"
+}
+
+// IsKeyword returns true for keyword tokens
+func (t Token) IsKeyword() bool {
+	return t >= CLASS && t <= RETURN
+}
+
+// IsSymbol returns true for symbol tokens
+func (t Token) IsSymbol() bool {
+	return t >= LBRACE && t <= TILDE
+}
+
+// IsOp returns true for binary operators
+func (t Token) IsOp() bool {
+	switch t {
+	case PLUS, MINUS, ASTERISK, SLASH, AMPERSAND, PIPE, LT, GT, EQUALS:
+		return true
+	}
+	return false
+}
+
+var eof = rune(0)
+
+func isWhitespace(ch rune) bool {
+	return ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r'
+}
+
+func isLetter(ch rune) bool {
+	return (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
+}
+
+// returns true if the character belongs to the class of allowed identifier characters
+func isIdent(ch rune) bool {
+	return isLetter(ch) || isDigit(ch) || ch == '_'
+}
+
+func isDigit(ch rune) bool {
+	return ch >= '0' && ch <= '9'
+}
+
+// Pos is a position in a source file
+type Pos struct {
+	File string
+	Line int
+	Col  int
+}
+
+func (p Pos) String() string {
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Col)
+}
+
+// Scanner can scan tokens
+type Scanner struct {
+	r *bufio.Reader
+
+	file    string
+	line    int
+	col     int
+	prevCol int
+	last    rune
+}
+
+// NewScanner creates a new scanner, which reads from the given Reader r.
+// The file name is used for positions.
+func NewScanner(r io.Reader, fileName string) *Scanner {
+	return &Scanner{
+		r: bufio.NewReader(r),
+
+		file: fileName,
+		line: 1,
+		col:  1,
+	}
+}
+
+// Pos returns the position of the next character
+func (s *Scanner) Pos() Pos {
+	return Pos{
+		File: s.file,
+		Line: s.line,
+		Col:  s.col,
+	}
+}
+
+func (s *Scanner) read() (rune, error) {
+	ch, _, err := s.r.ReadRune()
+	if err != nil {
+		if err == io.EOF {
+			s.last = eof
+			return eof, nil
+		}
+		return eof, err
+	}
+	s.last = ch
+	s.prevCol = s.col
+	if ch == '\n' {
+		s.line++
+		s.col = 1
+	} else {
+		s.col++
+	}
+	return ch, nil
+}
+
+func (s *Scanner) unread() error {
+	if s.last == eof {
+		return nil
+	}
+	if s.last == '\n' {
+		s.line--
+	}
+	s.col = s.prevCol
+	return s.r.UnreadRune()
+}
+
+// Scan returns the next token and its literal
+func (s *Scanner) Scan() (tok Token, lit string, err error) {
+	ch, err := s.read()
+	if err != nil {
+		return ILLEGAL, "", err
+	}
+
+	if isWhitespace(ch) {
+		err := s.unread()
+		if err != nil {
+			return ILLEGAL, "", err
+		}
+		return s.scanWhitespace()
+	} else if isLetter(ch) || ch == '_' {
+		err := s.unread()
+		if err != nil {
+			return ILLEGAL, "", err
+		}
+		return s.scanIdent()
+	} else if isDigit(ch) {
+		err := s.unread()
+		if err != nil {
+			return ILLEGAL, "", err
+		}
+		return s.scanInt()
+	}
+
+	switch ch {
+	case eof:
+		return EOF, "", nil
+	case '"':
+		return s.scanString()
+	case '/':
+		next, err := s.read()
+		if err != nil {
+			return ILLEGAL, "", err
+		}
+		if next == '/' {
+			return s.scanLineComment()
+		}
+		if next == '*' {
+			return s.scanBlockComment()
+		}
+		err = s.unread()
+		if err != nil {
+			return ILLEGAL, "", err
+		}
+		return SLASH, "/", nil
+	}
+	if tok, ok := symbols[ch]; ok {
+		return tok, string(ch), nil
+	}
+
+	return ILLEGAL, string(ch), nil
+}
+
+func (s *Scanner) scanWhitespace() (tok Token, lit string, err error) {
+	var buf bytes.Buffer
+	for {
+		if ch, err := s.read(); err != nil {
+			return ILLEGAL, "", err
+		} else if ch == eof {
+			break
+		} else if !isWhitespace(ch) {
+			err = s.unread()
+			if err != nil {
+				return ILLEGAL, "", err
+			}
+			break
+		} else {
+			buf.WriteRune(ch)
+		}
+	}
+
+	return WS, buf.String(), nil
+}
+
+func (s *Scanner) scanIdent() (tok Token, lit string, err error) {
+	var buf bytes.Buffer
+	for {
+		if ch, err := s.read(); err != nil {
+			return ILLEGAL, "", err
+		} else if !isIdent(ch) {
+			err = s.unread()
+			if err != nil {
+				return ILLEGAL, "", err
+			}
+			break
+		} else {
+			buf.WriteRune(ch)
+		}
+	}
+
+	if tok, ok := keywords[buf.String()]; ok {
+		return tok, buf.String(), nil
+	}
+	return IDENT, buf.String(), nil
+}
+
+func (s *Scanner) scanInt() (tok Token, lit string, err error) {
+	var buf bytes.Buffer
+	for {
+		if ch, err := s.read(); err != nil {
+			return ILLEGAL, "", err
+		} else if !isDigit(ch) {
+			err = s.unread()
+			if err != nil {
+				return ILLEGAL, "", err
+			}
+			break
+		} else {
+			buf.WriteRune(ch)
+		}
+	}
+
+	return INT, buf.String(), nil
+}
+
+func (s *Scanner) scanString() (tok Token, lit string, err error) {
+	var buf bytes.Buffer
+	for {
+		if ch, err := s.read(); err != nil {
+			return ILLEGAL, "", err
+		} else if ch == eof || ch == '\n' {
+			return ILLEGAL, buf.String(), fmt.Errorf("unterminated string constant")
+		} else if ch == '"' {
+			break
+		} else {
+			buf.WriteRune(ch)
+		}
+	}
+
+	return STRING, buf.String(), nil
+}
+
+func (s *Scanner) scanLineComment() (tok Token, lit string, err error) {
+	var buf bytes.Buffer
+	for {
+		if ch, err := s.read(); err != nil {
+			return ILLEGAL, "", err
+		} else if ch == eof {
+			break
+		} else if ch == '\n' {
+			err = s.unread()
+			if err != nil {
+				return ILLEGAL, "", err
+			}
+			break
+		} else {
+			buf.WriteRune(ch)
+		}
+	}
+
+	return COMMENT, buf.String(), nil
+}
+
+func (s *Scanner) scanBlockComment() (tok Token, lit string, err error) {
+	var buf bytes.Buffer
+	star := false
+	for {
+		ch, err := s.read()
+		if err != nil {
+			return ILLEGAL, "", err
+		}
+		if ch == eof {
+			return ILLEGAL, buf.String(), fmt.Errorf("unterminated comment")
+		}
+		if star && ch == '/' {
+			break
+		}
+		star = ch == '*'
+		buf.WriteRune(ch)
+	}
+
+	return COMMENT, buf.String(), nil
+}
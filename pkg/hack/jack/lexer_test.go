@@ -0,0 +1,93 @@
+package jack_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wongak/nand2tetris/pkg/hack/jack"
+)
+
+type expectation struct {
+	tok  jack.Token
+	lit  string
+	line int
+	col  int
+}
+
+func TestScan(t *testing.T) {
+	input := `/** doc comment */
+class Main {
+	// comment
+	let x[i] = "a string" + 123;
+	do Out.print(-x, ~y / z);
+}`
+	expect := []expectation{
+		{jack.CLASS, "class", 2, 1},
+		{jack.IDENT, "Main", 2, 7},
+		{jack.LBRACE, "{", 2, 12},
+		{jack.LET, "let", 4, 2},
+		{jack.IDENT, "x", 4, 6},
+		{jack.LBRACKET, "[", 4, 7},
+		{jack.IDENT, "i", 4, 8},
+		{jack.RBRACKET, "]", 4, 9},
+		{jack.EQUALS, "=", 4, 11},
+		{jack.STRING, "a string", 4, 13},
+		{jack.PLUS, "+", 4, 24},
+		{jack.INT, "123", 4, 26},
+		{jack.SEMICOLON, ";", 4, 29},
+		{jack.DO, "do", 5, 2},
+		{jack.IDENT, "Out", 5, 5},
+		{jack.DOT, ".", 5, 8},
+		{jack.IDENT, "print", 5, 9},
+		{jack.LPAREN, "(", 5, 14},
+		{jack.MINUS, "-", 5, 15},
+		{jack.IDENT, "x", 5, 16},
+		{jack.COMMA, ",", 5, 17},
+		{jack.TILDE, "~", 5, 19},
+		{jack.IDENT, "y", 5, 20},
+		{jack.SLASH, "/", 5, 22},
+		{jack.IDENT, "z", 5, 24},
+		{jack.RPAREN, ")", 5, 25},
+		{jack.SEMICOLON, ";", 5, 26},
+		{jack.RBRACE, "}", 6, 1},
+	}
+
+	sc := jack.NewScanner(strings.NewReader(input), "Main.jack")
+	i := 0
+	for {
+		pos := sc.Pos()
+		tok, lit, err := sc.Scan()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if tok == jack.EOF {
+			break
+		}
+		if tok == jack.WS || tok == jack.COMMENT {
+			continue
+		}
+		if i >= len(expect) {
+			t.Fatalf("unexpected token %s (%s)", tok, lit)
+		}
+		e := expect[i]
+		if tok != e.tok || lit != e.lit {
+			t.Errorf("expect %s (%s), got %s (%s)", e.tok, e.lit, tok, lit)
+		}
+		if pos.Line != e.line || pos.Col != e.col {
+			t.Errorf("expect %s on %d:%d, got %d:%d", e.lit, e.line, e.col, pos.Line, pos.Col)
+		}
+		i++
+	}
+	if i != len(expect) {
+		t.Errorf("expect %d tokens, got %d", len(expect), i)
+	}
+}
+
+func TestScanUnterminatedString(t *testing.T) {
+	sc := jack.NewScanner(strings.NewReader(`"abc
+"`), "Main.jack")
+	_, _, err := sc.Scan()
+	if err == nil {
+		t.Error("expect error on unterminated string")
+	}
+}
@@ -0,0 +1,650 @@
+package jack
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Error is a syntax error at a source position
+type Error struct {
+	Pos Pos
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Pos, e.Msg)
+}
+
+// Parser is a recursive descent parser for Jack classes
+type Parser struct {
+	s   *Scanner
+	buf struct {
+		tok         Token
+		lit         string
+		pos         Pos
+		isUnscanned bool
+	}
+
+	class *Class
+}
+
+// NewParser creates a new parser on the given Reader r. The file name is
+// used for error positions.
+func NewParser(r io.Reader, fileName string) *Parser {
+	return &Parser{
+		s: NewScanner(r, fileName),
+	}
+}
+
+func (p *Parser) scan() (tok Token, lit string, err error) {
+	if p.buf.isUnscanned {
+		p.buf.isUnscanned = false
+		return p.buf.tok, p.buf.lit, nil
+	}
+
+	pos := p.s.Pos()
+	tok, lit, err = p.s.Scan()
+	p.buf.pos = pos
+	if err != nil {
+		return ILLEGAL, "", &Error{Pos: pos, Msg: err.Error()}
+	}
+
+	p.buf.tok = tok
+	p.buf.lit = lit
+
+	return
+}
+
+func (p *Parser) unscan() {
+	p.buf.isUnscanned = true
+}
+
+// scanIgnore ignores whitespace and comments
+func (p *Parser) scanIgnore() (tok Token, lit string, err error) {
+	for {
+		tok, lit, err = p.scan()
+		if err != nil {
+			return ILLEGAL, "", err
+		}
+		if tok == WS || tok == COMMENT {
+			continue
+		}
+		return
+	}
+}
+
+// peek returns the next token without consuming it
+func (p *Parser) peek() (Token, error) {
+	tok, _, err := p.scanIgnore()
+	if err != nil {
+		return ILLEGAL, err
+	}
+	p.unscan()
+	return tok, nil
+}
+
+// pos returns the position of the last scanned token
+func (p *Parser) pos() Pos {
+	return p.buf.pos
+}
+
+func (p *Parser) errorf(format string, args ...interface{}) error {
+	return &Error{Pos: p.pos(), Msg: fmt.Sprintf(format, args...)}
+}
+
+func describe(tok Token, lit string) string {
+	switch tok {
+	case EOF:
+		return "end of file"
+	case IDENT, INT, ILLEGAL:
+		return fmt.Sprintf("%s %q", tok, lit)
+	case STRING:
+		return fmt.Sprintf("string %q", lit)
+	}
+	return fmt.Sprintf("%q", tok.String())
+}
+
+// expect scans the next token and fails if it is not tok
+func (p *Parser) expect(tok Token) (string, error) {
+	got, lit, err := p.scanIgnore()
+	if err != nil {
+		return "", err
+	}
+	if got != tok {
+		if tok == IDENT {
+			return "", p.errorf("expect identifier, got %s", describe(got, lit))
+		}
+		return "", p.errorf("expect %q, got %s", tok.String(), describe(got, lit))
+	}
+	return lit, nil
+}
+
+// accept consumes the next token if it is tok
+func (p *Parser) accept(tok Token) (bool, error) {
+	got, _, err := p.scanIgnore()
+	if err != nil {
+		return false, err
+	}
+	if got != tok {
+		p.unscan()
+		return false, nil
+	}
+	return true, nil
+}
+
+// Run parses a single class
+func (p *Parser) Run() error {
+	class, err := p.parseClass()
+	if err != nil {
+		return err
+	}
+	tok, lit, err := p.scanIgnore()
+	if err != nil {
+		return err
+	}
+	if tok != EOF {
+		return p.errorf("unexpected %s after class", describe(tok, lit))
+	}
+	p.class = class
+	return nil
+}
+
+// Tree returns the parsed class
+func (p *Parser) Tree() *Class {
+	return p.class
+}
+
+func (p *Parser) parseClass() (*Class, error) {
+	_, err := p.expect(CLASS)
+	if err != nil {
+		return nil, err
+	}
+	c := &Class{node: node{p.pos()}}
+	c.Name, err = p.expect(IDENT)
+	if err != nil {
+		return nil, err
+	}
+	_, err = p.expect(LBRACE)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		tok, lit, err := p.scanIgnore()
+		if err != nil {
+			return nil, err
+		}
+		switch tok {
+		case RBRACE:
+			return c, nil
+		case STATIC, FIELD:
+			if len(c.Subroutines) > 0 {
+				return nil, p.errorf("class variables must be declared before subroutines")
+			}
+			p.unscan()
+			v, err := p.parseClassVarDec()
+			if err != nil {
+				return nil, err
+			}
+			c.Vars = append(c.Vars, v)
+		case CONSTRUCTOR, FUNCTION, METHOD:
+			p.unscan()
+			s, err := p.parseSubroutine()
+			if err != nil {
+				return nil, err
+			}
+			c.Subroutines = append(c.Subroutines, s)
+		default:
+			return nil, p.errorf("expect class variable or subroutine declaration, got %s", describe(tok, lit))
+		}
+	}
+}
+
+// parseType parses int, char, boolean, a class name or if allowed void
+func (p *Parser) parseType(allowVoid bool) (Type, error) {
+	tok, lit, err := p.scanIgnore()
+	if err != nil {
+		return Type{}, err
+	}
+	switch tok {
+	case INTTYPE, CHAR, BOOLEAN:
+		return Type{Token: tok}, nil
+	case IDENT:
+		return Type{Token: tok, Name: lit}, nil
+	case VOID:
+		if allowVoid {
+			return Type{Token: tok}, nil
+		}
+	}
+	return Type{}, p.errorf("expect type, got %s", describe(tok, lit))
+}
+
+// parseNames parses a comma separated list of variable names up to ;
+func (p *Parser) parseNames() ([]string, error) {
+	names := make([]string, 0)
+	for {
+		name, err := p.expect(IDENT)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+		tok, lit, err := p.scanIgnore()
+		if err != nil {
+			return nil, err
+		}
+		if tok == SEMICOLON {
+			return names, nil
+		}
+		if tok != COMMA {
+			return nil, p.errorf("expect \",\" or \";\", got %s", describe(tok, lit))
+		}
+	}
+}
+
+func (p *Parser) parseClassVarDec() (*ClassVarDec, error) {
+	tok, _, err := p.scanIgnore()
+	if err != nil {
+		return nil, err
+	}
+	v := &ClassVarDec{node: node{p.pos()}, Kind: tok}
+	v.Type, err = p.parseType(false)
+	if err != nil {
+		return nil, err
+	}
+	v.Names, err = p.parseNames()
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func (p *Parser) parseSubroutine() (*Subroutine, error) {
+	tok, _, err := p.scanIgnore()
+	if err != nil {
+		return nil, err
+	}
+	s := &Subroutine{node: node{p.pos()}, Kind: tok}
+	s.ReturnType, err = p.parseType(true)
+	if err != nil {
+		return nil, err
+	}
+	s.Name, err = p.expect(IDENT)
+	if err != nil {
+		return nil, err
+	}
+
+	// parameter list
+	_, err = p.expect(LPAREN)
+	if err != nil {
+		return nil, err
+	}
+	ok, err := p.accept(RPAREN)
+	if err != nil {
+		return nil, err
+	}
+	for !ok {
+		t, err := p.parseType(false)
+		if err != nil {
+			return nil, err
+		}
+		param := &Param{node: node{p.pos()}, Type: t}
+		param.Name, err = p.expect(IDENT)
+		if err != nil {
+			return nil, err
+		}
+		s.Params = append(s.Params, param)
+
+		tok, lit, err := p.scanIgnore()
+		if err != nil {
+			return nil, err
+		}
+		if tok == RPAREN {
+			break
+		}
+		if tok != COMMA {
+			return nil, p.errorf("expect \",\" or \")\", got %s", describe(tok, lit))
+		}
+	}
+
+	// body
+	_, err = p.expect(LBRACE)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		ok, err := p.accept(VAR)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		v := &VarDec{node: node{p.pos()}}
+		v.Type, err = p.parseType(false)
+		if err != nil {
+			return nil, err
+		}
+		v.Names, err = p.parseNames()
+		if err != nil {
+			return nil, err
+		}
+		s.Vars = append(s.Vars, v)
+	}
+	s.Body, err = p.parseStatements()
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// parseStatements parses statements up to and including the closing brace
+func (p *Parser) parseStatements() ([]Statement, error) {
+	stmts := make([]Statement, 0)
+	for {
+		tok, lit, err := p.scanIgnore()
+		if err != nil {
+			return nil, err
+		}
+		var stmt Statement
+		switch tok {
+		case RBRACE:
+			return stmts, nil
+		case LET:
+			stmt, err = p.parseLet()
+		case IF:
+			stmt, err = p.parseIf()
+		case WHILE:
+			stmt, err = p.parseWhile()
+		case DO:
+			stmt, err = p.parseDo()
+		case RETURN:
+			stmt, err = p.parseReturn()
+		default:
+			return nil, p.errorf("expect statement, got %s", describe(tok, lit))
+		}
+		if err != nil {
+			return nil, err
+		}
+		stmts = append(stmts, stmt)
+	}
+}
+
+func (p *Parser) parseLet() (Statement, error) {
+	stmt := &LetStatement{node: node{p.pos()}}
+	var err error
+	stmt.Name, err = p.expect(IDENT)
+	if err != nil {
+		return nil, err
+	}
+	ok, err := p.accept(LBRACKET)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		stmt.Index, err = p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		_, err = p.expect(RBRACKET)
+		if err != nil {
+			return nil, err
+		}
+	}
+	_, err = p.expect(EQUALS)
+	if err != nil {
+		return nil, err
+	}
+	stmt.Value, err = p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	_, err = p.expect(SEMICOLON)
+	if err != nil {
+		return nil, err
+	}
+	return stmt, nil
+}
+
+// parseCondition parses ( expression ) {
+func (p *Parser) parseCondition() (Expression, error) {
+	_, err := p.expect(LPAREN)
+	if err != nil {
+		return nil, err
+	}
+	cond, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	_, err = p.expect(RPAREN)
+	if err != nil {
+		return nil, err
+	}
+	_, err = p.expect(LBRACE)
+	if err != nil {
+		return nil, err
+	}
+	return cond, nil
+}
+
+func (p *Parser) parseIf() (Statement, error) {
+	stmt := &IfStatement{node: node{p.pos()}}
+	var err error
+	stmt.Cond, err = p.parseCondition()
+	if err != nil {
+		return nil, err
+	}
+	stmt.Then, err = p.parseStatements()
+	if err != nil {
+		return nil, err
+	}
+	ok, err := p.accept(ELSE)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		_, err = p.expect(LBRACE)
+		if err != nil {
+			return nil, err
+		}
+		stmt.Else, err = p.parseStatements()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return stmt, nil
+}
+
+func (p *Parser) parseWhile() (Statement, error) {
+	stmt := &WhileStatement{node: node{p.pos()}}
+	var err error
+	stmt.Cond, err = p.parseCondition()
+	if err != nil {
+		return nil, err
+	}
+	stmt.Body, err = p.parseStatements()
+	if err != nil {
+		return nil, err
+	}
+	return stmt, nil
+}
+
+func (p *Parser) parseDo() (Statement, error) {
+	stmt := &DoStatement{node: node{p.pos()}}
+	name, err := p.expect(IDENT)
+	if err != nil {
+		return nil, err
+	}
+	stmt.Call, err = p.parseCall(p.pos(), name)
+	if err != nil {
+		return nil, err
+	}
+	_, err = p.expect(SEMICOLON)
+	if err != nil {
+		return nil, err
+	}
+	return stmt, nil
+}
+
+func (p *Parser) parseReturn() (Statement, error) {
+	stmt := &ReturnStatement{node: node{p.pos()}}
+	ok, err := p.accept(SEMICOLON)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		return stmt, nil
+	}
+	stmt.Value, err = p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	_, err = p.expect(SEMICOLON)
+	if err != nil {
+		return nil, err
+	}
+	return stmt, nil
+}
+
+// parseCall parses the rest of a subroutine call after the first identifier
+// name or name.name
+func (p *Parser) parseCall(pos Pos, name string) (*CallExpr, error) {
+	call := &CallExpr{node: node{pos}, Name: name}
+	ok, err := p.accept(DOT)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		call.Receiver = name
+		call.Name, err = p.expect(IDENT)
+		if err != nil {
+			return nil, err
+		}
+	}
+	_, err = p.expect(LPAREN)
+	if err != nil {
+		return nil, err
+	}
+	call.Args, err = p.parseExpressionList()
+	if err != nil {
+		return nil, err
+	}
+	return call, nil
+}
+
+// parseExpressionList parses comma separated expressions up to and
+// including the closing parenthesis
+func (p *Parser) parseExpressionList() ([]Expression, error) {
+	exprs := make([]Expression, 0)
+	ok, err := p.accept(RPAREN)
+	if err != nil {
+		return nil, err
+	}
+	for !ok {
+		expr, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, expr)
+
+		tok, lit, err := p.scanIgnore()
+		if err != nil {
+			return nil, err
+		}
+		if tok == RPAREN {
+			break
+		}
+		if tok != COMMA {
+			return nil, p.errorf("expect \",\" or \")\", got %s", describe(tok, lit))
+		}
+	}
+	return exprs, nil
+}
+
+// parseExpression parses term (op term)*
+func (p *Parser) parseExpression() (Expression, error) {
+	expr, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok, err := p.peek()
+		if err != nil {
+			return nil, err
+		}
+		if !tok.IsOp() {
+			return expr, nil
+		}
+		_, _, err = p.scanIgnore()
+		if err != nil {
+			return nil, err
+		}
+		bin := &BinaryExpr{node: node{p.pos()}, Op: tok, Left: expr}
+		bin.Right, err = p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		expr = bin
+	}
+}
+
+func (p *Parser) parseTerm() (Expression, error) {
+	tok, lit, err := p.scanIgnore()
+	if err != nil {
+		return nil, err
+	}
+	pos := p.pos()
+	switch tok {
+	case INT:
+		n, err := strconv.Atoi(lit)
+		if err != nil || n > 32767 {
+			return nil, p.errorf("integer constant %s out of range 0..32767", lit)
+		}
+		return &IntegerConst{node: node{pos}, Value: n}, nil
+	case STRING:
+		return &StringConst{node: node{pos}, Value: lit}, nil
+	case TRUE, FALSE, NULL, THIS:
+		return &KeywordConst{node: node{pos}, Keyword: tok}, nil
+	case LPAREN:
+		expr, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		_, err = p.expect(RPAREN)
+		if err != nil {
+			return nil, err
+		}
+		return expr, nil
+	case MINUS, TILDE:
+		operand, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		return &UnaryExpr{node: node{pos}, Op: tok, Operand: operand}, nil
+	case IDENT:
+		next, err := p.peek()
+		if err != nil {
+			return nil, err
+		}
+		switch next {
+		case LBRACKET:
+			_, _, err = p.scanIgnore()
+			if err != nil {
+				return nil, err
+			}
+			index, err := p.parseExpression()
+			if err != nil {
+				return nil, err
+			}
+			_, err = p.expect(RBRACKET)
+			if err != nil {
+				return nil, err
+			}
+			return &IndexExpr{node: node{pos}, Name: lit, Index: index}, nil
+		case LPAREN, DOT:
+			return p.parseCall(pos, lit)
+		}
+		return &VarRef{node: node{pos}, Name: lit}, nil
+	}
+	return nil, p.errorf("expect term, got %s", describe(tok, lit))
+}
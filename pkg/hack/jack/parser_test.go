@@ -0,0 +1,162 @@
+package jack_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wongak/nand2tetris/pkg/hack/jack"
+)
+
+const squareGame = `
+/** Implements a square game. */
+class SquareGame {
+	field Square square; // the square
+	field int direction;
+	static boolean debug;
+
+	constructor SquareGame new() {
+		let square = Square.new(0, 0, 30);
+		let direction = 0;
+		return this;
+	}
+
+	method void moveSquare() {
+		var int i, j;
+		var Array a;
+		if (direction = 1) { do square.moveUp(); }
+		else {
+			if (~(direction < 2)) { do moveDown(); }
+		}
+		while (i < 10) {
+			let a[i + 1] = -i * 2;
+			let i = i + 1;
+		}
+		do Sys.wait(5);
+		return;
+	}
+}
+`
+
+func parse(t *testing.T, src string) *jack.Class {
+	p := jack.NewParser(strings.NewReader(src), "SquareGame.jack")
+	err := p.Run()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return p.Tree()
+}
+
+func TestParseClass(t *testing.T) {
+	c := parse(t, squareGame)
+	if c.Name != "SquareGame" {
+		t.Errorf("expect class SquareGame, got %s", c.Name)
+	}
+	if len(c.Vars) != 3 {
+		t.Fatalf("expect 3 class var decs, got %d", len(c.Vars))
+	}
+	if c.Vars[0].Kind != jack.FIELD || c.Vars[0].Type.String() != "Square" {
+		t.Errorf("unexpected class var %+v", c.Vars[0])
+	}
+	if c.Vars[2].Kind != jack.STATIC || c.Vars[2].Type.Token != jack.BOOLEAN {
+		t.Errorf("unexpected class var %+v", c.Vars[2])
+	}
+	if len(c.Subroutines) != 2 {
+		t.Fatalf("expect 2 subroutines, got %d", len(c.Subroutines))
+	}
+
+	ctor := c.Subroutines[0]
+	if ctor.Kind != jack.CONSTRUCTOR || ctor.Name != "new" || ctor.ReturnType.Name != "SquareGame" {
+		t.Errorf("unexpected constructor %+v", ctor)
+	}
+	let, ok := ctor.Body[0].(*jack.LetStatement)
+	if !ok {
+		t.Fatalf("expect let statement, got %T", ctor.Body[0])
+	}
+	call, ok := let.Value.(*jack.CallExpr)
+	if !ok || call.Receiver != "Square" || call.Name != "new" || len(call.Args) != 3 {
+		t.Errorf("unexpected call %+v", let.Value)
+	}
+	ret := ctor.Body[2].(*jack.ReturnStatement)
+	if kw, ok := ret.Value.(*jack.KeywordConst); !ok || kw.Keyword != jack.THIS {
+		t.Errorf("expect return this, got %+v", ret.Value)
+	}
+
+	m := c.Subroutines[1]
+	if m.Kind != jack.METHOD || m.ReturnType.Token != jack.VOID {
+		t.Errorf("unexpected method %+v", m)
+	}
+	if len(m.Vars) != 2 || len(m.Vars[0].Names) != 2 {
+		t.Errorf("unexpected var decs %+v", m.Vars)
+	}
+	if len(m.Body) != 4 {
+		t.Fatalf("expect 4 statements, got %d", len(m.Body))
+	}
+	ifStmt := m.Body[0].(*jack.IfStatement)
+	if len(ifStmt.Then) != 1 || len(ifStmt.Else) != 1 {
+		t.Errorf("unexpected if statement %+v", ifStmt)
+	}
+	inner := ifStmt.Else[0].(*jack.IfStatement)
+	if u, ok := inner.Cond.(*jack.UnaryExpr); !ok || u.Op != jack.TILDE {
+		t.Errorf("expect unary not, got %+v", inner.Cond)
+	}
+	do := inner.Then[0].(*jack.DoStatement)
+	if do.Call.Receiver != "" || do.Call.Name != "moveDown" {
+		t.Errorf("unexpected do %+v", do.Call)
+	}
+
+	while := m.Body[1].(*jack.WhileStatement)
+	arrLet := while.Body[0].(*jack.LetStatement)
+	if _, ok := arrLet.Index.(*jack.BinaryExpr); !ok {
+		t.Errorf("expect binary index expression, got %T", arrLet.Index)
+	}
+	// -i * 2 is evaluated left to right: (-i) * 2
+	mul, ok := arrLet.Value.(*jack.BinaryExpr)
+	if !ok || mul.Op != jack.ASTERISK {
+		t.Fatalf("expect multiplication, got %+v", arrLet.Value)
+	}
+	if _, ok := mul.Left.(*jack.UnaryExpr); !ok {
+		t.Errorf("expect unary minus on the left, got %T", mul.Left)
+	}
+	if pos := while.Pos(); pos.Line != 21 || pos.Col != 3 {
+		t.Errorf("expect while on 21:3, got %s", pos)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	cases := []struct {
+		src    string
+		expect string
+	}{
+		{
+			src:    "class Main {\n  function void main() {\n    let x = ;\n  }\n}",
+			expect: "Main.jack:3:13: expect term, got \";\"",
+		},
+		{
+			src:    "class Main {\n  function void main() {\n    do Output.print(1 2);\n  }\n}",
+			expect: "Main.jack:3:23: expect \",\" or \")\", got INT \"2\"",
+		},
+		{
+			src:    "class Main {\n  field void x;\n}",
+			expect: "Main.jack:2:9: expect type, got \"void\"",
+		},
+		{
+			src:    "class Main {\n  function int f() {\n    return 40000;\n  }\n}",
+			expect: "Main.jack:3:12: integer constant 40000 out of range 0..32767",
+		},
+		{
+			src:    "class Main {\n  function void main() {\n    return;\n  }\n",
+			expect: "Main.jack:5:1: expect class variable or subroutine declaration, got end of file",
+		},
+	}
+	for _, c := range cases {
+		p := jack.NewParser(strings.NewReader(c.src), "Main.jack")
+		err := p.Run()
+		if err == nil {
+			t.Errorf("expect error %s", c.expect)
+			continue
+		}
+		if err.Error() != c.expect {
+			t.Errorf("expect error\n%s, got\n%s", c.expect, err)
+		}
+	}
+}
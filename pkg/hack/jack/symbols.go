@@ -0,0 +1,94 @@
+package jack
+
+import (
+	vm "github.com/wongak/nand2tetris/pkg/hack/vm/language"
+)
+
+// Kind is the kind of a variable
+type Kind int
+
+const (
+	KindStatic Kind = iota
+	KindField
+	KindArg
+	KindVar
+)
+
+// Segment returns the VM segment holding variables of the kind
+func (k Kind) Segment() vm.Token {
+	switch k {
+	case KindStatic:
+		return vm.STATIC
+	case KindField:
+		return vm.THIS
+	case KindArg:
+		return vm.ARG
+	default:
+		return vm.LCL
+	}
+}
+
+// Symbol is a variable in scope
+type Symbol struct {
+	Name  string
+	Type  Type
+	Kind  Kind
+	Index int
+}
+
+// SymbolTable holds the class and subroutine scope
+type SymbolTable struct {
+	class      map[string]*Symbol
+	subroutine map[string]*Symbol
+	count      map[Kind]int
+}
+
+// NewSymbolTable creates an empty symbol table
+func NewSymbolTable() *SymbolTable {
+	return &SymbolTable{
+		class:      make(map[string]*Symbol),
+		subroutine: make(map[string]*Symbol),
+		count:      make(map[Kind]int),
+	}
+}
+
+// StartSubroutine resets the subroutine scope
+func (t *SymbolTable) StartSubroutine() {
+	t.subroutine = make(map[string]*Symbol)
+	t.count[KindArg] = 0
+	t.count[KindVar] = 0
+}
+
+// Define adds a variable to the scope of its kind. It returns false if
+// the name is already defined in that scope.
+func (t *SymbolTable) Define(name string, typ Type, kind Kind) bool {
+	scope := t.subroutine
+	if kind == KindStatic || kind == KindField {
+		scope = t.class
+	}
+	if _, ok := scope[name]; ok {
+		return false
+	}
+	scope[name] = &Symbol{
+		Name:  name,
+		Type:  typ,
+		Kind:  kind,
+		Index: t.count[kind],
+	}
+	t.count[kind]++
+	return true
+}
+
+// Count returns the number of variables of the kind
+func (t *SymbolTable) Count(kind Kind) int {
+	return t.count[kind]
+}
+
+// Lookup finds a variable in the subroutine and then the class scope
+func (t *SymbolTable) Lookup(name string) (*Symbol, bool) {
+	if s, ok := t.subroutine[name]; ok {
+		return s, true
+	}
+	s, ok := t.class[name]
+	return s, ok
+}
@@ -0,0 +1,239 @@
+package tst
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Command is a command of a test script
+type Command interface {
+	fmt.Stringer
+
+	Line() int
+	Exec(*Interpreter) error
+}
+
+// pos is the line of a command in the script
+type pos struct {
+	line int
+}
+
+// Line returns the line of the command in the script
+func (p pos) Line() int {
+	return p.line
+}
+
+type (
+	// Load loads a .hack or .asm program into the ROM
+	Load struct {
+		pos
+		File string
+	}
+
+	// OutputFile sets the file the output is written to
+	OutputFile struct {
+		pos
+		File string
+	}
+
+	// CompareTo sets the file the output is compared to
+	CompareTo struct {
+		pos
+		File string
+	}
+
+	// OutputList sets the output variables and writes the header
+	OutputList struct {
+		pos
+		Vars []OutputVar
+	}
+
+	// Output writes the current values of the output variables
+	Output struct {
+		pos
+	}
+
+	// Set sets a variable (A, D, PC, RAM[i] or ROM[i])
+	Set struct {
+		pos
+		Var   string
+		Value int16
+	}
+
+	// Tick is the first half of a clock cycle
+	Tick struct {
+		pos
+	}
+
+	// Tock is the second half of a clock cycle and executes an instruction
+	Tock struct {
+		pos
+	}
+
+	// TickTock executes an instruction
+	TickTock struct {
+		pos
+	}
+
+	// Echo prints a message
+	Echo struct {
+		pos
+		Text string
+	}
+
+	// Repeat repeats its body Count times
+	Repeat struct {
+		pos
+		Count int
+		Body  []Command
+	}
+
+	// While repeats its body as long as the condition holds
+	While struct {
+		pos
+		Cond Condition
+		Body []Command
+	}
+
+	// Condition compares a variable to a value
+	Condition struct {
+		Var   string
+		Op    string
+		Value int16
+	}
+)
+
+func newCommand(line int, name string, args []string) (Command, error) {
+	expectArgs := func(n int) error {
+		if len(args) != n {
+			return fmt.Errorf("%s expects %d argument(s), got %d", name, n, len(args))
+		}
+		return nil
+	}
+	switch name {
+	case "load":
+		if err := expectArgs(1); err != nil {
+			return nil, err
+		}
+		return &Load{pos: pos{line}, File: args[0]}, nil
+	case "output-file":
+		if err := expectArgs(1); err != nil {
+			return nil, err
+		}
+		return &OutputFile{pos: pos{line}, File: args[0]}, nil
+	case "compare-to":
+		if err := expectArgs(1); err != nil {
+			return nil, err
+		}
+		return &CompareTo{pos: pos{line}, File: args[0]}, nil
+	case "output-list":
+		cmd := &OutputList{pos: pos{line}}
+		for _, arg := range args {
+			v, err := parseOutputVar(arg)
+			if err != nil {
+				return nil, err
+			}
+			cmd.Vars = append(cmd.Vars, v)
+		}
+		return cmd, nil
+	case "output":
+		if err := expectArgs(0); err != nil {
+			return nil, err
+		}
+		return &Output{pos: pos{line}}, nil
+	case "set":
+		if err := expectArgs(2); err != nil {
+			return nil, err
+		}
+		value, err := parseValue(args[1])
+		if err != nil {
+			return nil, err
+		}
+		return &Set{pos: pos{line}, Var: args[0], Value: value}, nil
+	case "tick":
+		return &Tick{pos: pos{line}}, expectArgs(0)
+	case "tock":
+		return &Tock{pos: pos{line}}, expectArgs(0)
+	case "ticktock":
+		return &TickTock{pos: pos{line}}, expectArgs(0)
+	case "echo":
+		return &Echo{pos: pos{line}, Text: strings.Join(args, " ")}, nil
+	}
+	return nil, fmt.Errorf("unsupported command %s", name)
+}
+
+func newBlockCommand(line int, name string, args []string, body []Command) (Command, error) {
+	switch name {
+	case "repeat":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("repeat expects a count")
+		}
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid repeat count %s", args[0])
+		}
+		return &Repeat{pos: pos{line}, Count: n, Body: body}, nil
+	case "while":
+		if len(args) != 3 {
+			return nil, fmt.Errorf("while expects a condition var op value")
+		}
+		switch args[1] {
+		case "=", "<>", "<", ">", "<=", ">=":
+		default:
+			return nil, fmt.Errorf("invalid comparison %s", args[1])
+		}
+		value, err := parseValue(args[2])
+		if err != nil {
+			return nil, err
+		}
+		return &While{
+			pos:  pos{line},
+			Cond: Condition{Var: args[0], Op: args[1], Value: value},
+			Body: body,
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported block command %s", name)
+}
+
+func (c *Load) String() string       { return "load " + c.File }
+func (c *OutputFile) String() string { return "output-file " + c.File }
+func (c *CompareTo) String() string  { return "compare-to " + c.File }
+func (c *Output) String() string     { return "output" }
+func (c *Set) String() string        { return fmt.Sprintf("set %s %d", c.Var, c.Value) }
+func (c *Tick) String() string       { return "tick" }
+func (c *Tock) String() string       { return "tock" }
+func (c *TickTock) String() string   { return "ticktock" }
+func (c *Echo) String() string       { return fmt.Sprintf("echo %q", c.Text) }
+func (c *Repeat) String() string     { return fmt.Sprintf("repeat %d", c.Count) }
+func (c *While) String() string      { return "while " + c.Cond.String() }
+
+func (c *OutputList) String() string {
+	vars := make([]string, len(c.Vars))
+	for i, v := range c.Vars {
+		vars[i] = v.String()
+	}
+	return "output-list " + strings.Join(vars, " ")
+}
+
+func (c Condition) String() string {
+	return fmt.Sprintf("%s %s %d", c.Var, c.Op, c.Value)
+}
+
+func (c Condition) eval(value int16) bool {
+	switch c.Op {
+	case "=":
+		return value == c.Value
+	case "<>":
+		return value != c.Value
+	case "<":
+		return value < c.Value
+	case ">":
+		return value > c.Value
+	case "<=":
+		return value <= c.Value
+	case ">=":
+		return value >= c.Value
+	}
+	return false
+}
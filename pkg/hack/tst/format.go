@@ -0,0 +1,128 @@
+package tst
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// OutputVar is a variable of the output list with its format
+//
+// The format %D2.6.2 prints the variable in decimal right aligned in a
+// column of 6 characters with 2 spaces of padding on each side.
+type OutputVar struct {
+	Name   string
+	Format byte
+	PadL   int
+	Len    int
+	PadR   int
+}
+
+func parseOutputVar(str string) (OutputVar, error) {
+	v := OutputVar{
+		Format: 'B',
+		PadL:   1,
+		Len:    16,
+		PadR:   1,
+	}
+	i := strings.IndexByte(str, '%')
+	if i < 0 {
+		v.Name = str
+		return v, nil
+	}
+	v.Name = str[:i]
+	spec := str[i+1:]
+	if len(spec) < 2 {
+		return v, fmt.Errorf("invalid output format %s", str)
+	}
+	v.Format = spec[0]
+	switch v.Format {
+	case 'B', 'D', 'X', 'S':
+	default:
+		return v, fmt.Errorf("invalid output format %c in %s", v.Format, str)
+	}
+	parts := strings.Split(spec[1:], ".")
+	if len(parts) != 3 {
+		return v, fmt.Errorf("invalid output format %s. expect %%Fl.w.r", str)
+	}
+	nums := make([]int, 3)
+	for j, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return v, fmt.Errorf("invalid output format %s: %s", str, part)
+		}
+		nums[j] = n
+	}
+	v.PadL, v.Len, v.PadR = nums[0], nums[1], nums[2]
+	return v, nil
+}
+
+// String returns the variable in the output-list notation
+func (v OutputVar) String() string {
+	return fmt.Sprintf("%s%%%c%d.%d.%d", v.Name, v.Format, v.PadL, v.Len, v.PadR)
+}
+
+// Header returns the centered column header
+func (v OutputVar) Header() string {
+	space := v.PadL + v.Len + v.PadR
+	header := v.Name
+	if len(header) > space {
+		header = header[:space]
+	}
+	left := (space - len(header)) / 2
+	right := space - left - len(header)
+	return strings.Repeat(" ", left) + header + strings.Repeat(" ", right)
+}
+
+// Value returns the formatted column value
+func (v OutputVar) Value(value string) string {
+	var str string
+	n, err := strconv.Atoi(value)
+	switch {
+	case v.Format == 'S' || err != nil:
+		str = fmt.Sprintf("%-*s", v.Len, value)
+	case v.Format == 'D':
+		str = fmt.Sprintf("%*d", v.Len, n)
+	case v.Format == 'B':
+		str = lastN(fmt.Sprintf("%016b", uint16(n)), v.Len)
+	case v.Format == 'X':
+		str = lastN(fmt.Sprintf("%04X", uint16(n)), v.Len)
+	}
+	return strings.Repeat(" ", v.PadL) + str + strings.Repeat(" ", v.PadR)
+}
+
+func lastN(str string, n int) string {
+	if len(str) > n {
+		return str[len(str)-n:]
+	}
+	return strings.Repeat("0", n-len(str)) + str
+}
+
+// parseValue parses a value of the set command. Values may be given
+// as decimal or prefixed with %D, %X or %B.
+func parseValue(str string) (int16, error) {
+	base := 10
+	if strings.HasPrefix(str, "%") && len(str) > 2 {
+		switch str[1] {
+		case 'D':
+		case 'X':
+			base = 16
+		case 'B':
+			base = 2
+		default:
+			return 0, fmt.Errorf("invalid value %s", str)
+		}
+		str = str[2:]
+	}
+	n, err := strconv.ParseInt(str, base, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %s: %v", str, err)
+	}
+	if base != 10 {
+		return int16(uint16(n)), nil
+	}
+	if n < -32768 || n > 32767 {
+		return 0, fmt.Errorf("value %s out of range", str)
+	}
+	return int16(n), nil
+}
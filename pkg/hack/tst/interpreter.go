@@ -0,0 +1,384 @@
+package tst
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	asm "github.com/wongak/nand2tetris/pkg/hack/assembly/language"
+	"github.com/wongak/nand2tetris/pkg/hack/cpu"
+)
+
+// Interpreter executes test scripts against the Hack CPU emulator
+type Interpreter struct {
+	Machine *cpu.Machine
+
+	// Dir is the directory file names in the script are relative to
+	Dir string
+	// Echo receives the messages of the echo command
+	Echo io.Writer
+
+	out     *os.File
+	cmp     *bufio.Scanner
+	cmpFile string
+	vars    []OutputVar
+	line    int
+	time    int
+	tick    bool
+}
+
+// CompareError is returned if the output differs from the compare file
+type CompareError struct {
+	File     string
+	Line     int
+	Expected string
+	Actual   string
+}
+
+func (e *CompareError) Error() string {
+	marker := make([]byte, len(e.Actual))
+	for i := range marker {
+		marker[i] = ' '
+		if i >= len(e.Expected) || (e.Expected[i] != '*' && e.Expected[i] != e.Actual[i]) {
+			marker[i] = '^'
+		}
+	}
+	return fmt.Sprintf("comparison failure at line %d of %s:\n  expected: %s\n  actual:   %s\n            %s",
+		e.Line, e.File, e.Expected, e.Actual, strings.TrimRight(string(marker), " "))
+}
+
+// ExecError is returned if a command fails
+type ExecError struct {
+	Line int
+	Cmd  Command
+	Err  error
+}
+
+func (e *ExecError) Error() string {
+	return fmt.Sprintf("error on line %d (%s): %v", e.Line, e.Cmd, e.Err)
+}
+
+// NewInterpreter creates an interpreter with a new machine. File names
+// are resolved relative to dir.
+func NewInterpreter(dir string) *Interpreter {
+	return &Interpreter{
+		Machine: cpu.NewMachine(),
+		Dir:     dir,
+		Echo:    os.Stdout,
+	}
+}
+
+// RunFile parses and executes the test script fileName. Parse errors
+// are prefixed with the file name.
+func RunFile(fileName string) error {
+	in, err := os.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	p := NewParser(in)
+	err = p.Run()
+	if err != nil {
+		return fmt.Errorf("%s: %v", fileName, err)
+	}
+
+	interp := NewInterpreter(filepath.Dir(fileName))
+	defer interp.Close()
+	return interp.Run(p.Tree())
+}
+
+// Run executes the commands. It stops on the first error or comparison
+// failure.
+func (in *Interpreter) Run(cmds []Command) error {
+	for _, cmd := range cmds {
+		err := cmd.Exec(in)
+		switch err.(type) {
+		case nil:
+		case *CompareError, *ExecError:
+			return err
+		default:
+			return &ExecError{Line: cmd.Line(), Cmd: cmd, Err: err}
+		}
+	}
+	return nil
+}
+
+// Close closes the output and compare files
+func (in *Interpreter) Close() error {
+	in.cmp = nil
+	if in.out == nil {
+		return nil
+	}
+	err := in.out.Close()
+	in.out = nil
+	return err
+}
+
+func (in *Interpreter) path(fileName string) string {
+	if filepath.IsAbs(fileName) {
+		return fileName
+	}
+	return filepath.Join(in.Dir, fileName)
+}
+
+// writeLine writes a line to the output file and compares it
+func (in *Interpreter) writeLine(line string) error {
+	in.line++
+	if in.out != nil {
+		_, err := fmt.Fprintln(in.out, line)
+		if err != nil {
+			return err
+		}
+	}
+	if in.cmp == nil {
+		return nil
+	}
+	if !in.cmp.Scan() {
+		if err := in.cmp.Err(); err != nil {
+			return err
+		}
+		return &CompareError{File: in.cmpFile, Line: in.line, Actual: line}
+	}
+	expected := strings.TrimRight(in.cmp.Text(), "\r")
+	if !matches(expected, line) {
+		return &CompareError{File: in.cmpFile, Line: in.line, Expected: expected, Actual: line}
+	}
+	return nil
+}
+
+// matches compares the output line to the expected line. A * in the
+// expected line matches any character.
+func matches(expected, actual string) bool {
+	if len(expected) != len(actual) {
+		return false
+	}
+	for i := 0; i < len(expected); i++ {
+		if expected[i] != '*' && expected[i] != actual[i] {
+			return false
+		}
+	}
+	return true
+}
+
+var memoryVar = regexp.MustCompile(`^(RAM|ROM)\[(\d+)\]$`)
+
+// get returns the value of a variable as a string
+func (in *Interpreter) get(name string) (string, error) {
+	m := in.Machine
+	switch name {
+	case "A":
+		return strconv.Itoa(int(m.A)), nil
+	case "D":
+		return strconv.Itoa(int(m.D)), nil
+	case "PC":
+		return strconv.Itoa(int(m.PC)), nil
+	case "time":
+		if in.tick {
+			return strconv.Itoa(in.time) + "+", nil
+		}
+		return strconv.Itoa(in.time), nil
+	}
+	mem, addr, err := parseMemoryVar(name)
+	if err != nil {
+		return "", err
+	}
+	if mem == "ROM" {
+		return strconv.Itoa(int(int16(m.ROM[addr]))), nil
+	}
+	return strconv.Itoa(int(m.RAM[addr])), nil
+}
+
+// set sets the value of a variable
+func (in *Interpreter) set(name string, value int16) error {
+	m := in.Machine
+	switch name {
+	case "A":
+		m.A = value
+		return nil
+	case "D":
+		m.D = value
+		return nil
+	case "PC":
+		m.PC = uint16(value)
+		return nil
+	}
+	mem, addr, err := parseMemoryVar(name)
+	if err != nil {
+		return err
+	}
+	if mem == "ROM" {
+		m.ROM[addr] = uint16(value)
+		return nil
+	}
+	m.RAM[addr] = value
+	return nil
+}
+
+func parseMemoryVar(name string) (string, int, error) {
+	match := memoryVar.FindStringSubmatch(name)
+	if match == nil {
+		return "", 0, fmt.Errorf("unknown variable %s", name)
+	}
+	addr, err := strconv.Atoi(match[2])
+	if err != nil || addr >= cpu.MemorySize {
+		return "", 0, fmt.Errorf("invalid address in %s", name)
+	}
+	return match[1], addr, nil
+}
+
+// Exec loads the program
+func (c *Load) Exec(in *Interpreter) error {
+	fileName := in.path(c.File)
+	if filepath.Ext(fileName) != ".asm" {
+		f, err := os.Open(fileName)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return in.Machine.Load(f)
+	}
+
+	f, err := os.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	p := asm.NewParser(f)
+	err = p.Run()
+	if err != nil {
+		return fmt.Errorf("%s: %v", c.File, err)
+	}
+	buf := bytes.NewBuffer(nil)
+	err = asm.Assemble(asm.NewSymbolTable(), p.Tree(), buf)
+	if err != nil {
+		return fmt.Errorf("%s: %v", c.File, err)
+	}
+	return in.Machine.Load(buf)
+}
+
+// Exec opens the output file
+func (c *OutputFile) Exec(in *Interpreter) error {
+	if in.out != nil {
+		err := in.out.Close()
+		if err != nil {
+			return err
+		}
+	}
+	f, err := os.Create(in.path(c.File))
+	if err != nil {
+		return err
+	}
+	in.out = f
+	return nil
+}
+
+// Exec opens the compare file
+func (c *CompareTo) Exec(in *Interpreter) error {
+	content, err := os.ReadFile(in.path(c.File))
+	if err != nil {
+		return err
+	}
+	in.cmp = bufio.NewScanner(bytes.NewReader(content))
+	in.cmpFile = c.File
+	return nil
+}
+
+// Exec sets the output variables and writes the header line
+func (c *OutputList) Exec(in *Interpreter) error {
+	in.vars = c.Vars
+	var buf strings.Builder
+	for _, v := range c.Vars {
+		buf.WriteString("|")
+		buf.WriteString(v.Header())
+	}
+	buf.WriteString("|")
+	return in.writeLine(buf.String())
+}
+
+// Exec writes a line with the current values
+func (c *Output) Exec(in *Interpreter) error {
+	var buf strings.Builder
+	for _, v := range in.vars {
+		value, err := in.get(v.Name)
+		if err != nil {
+			return err
+		}
+		buf.WriteString("|")
+		buf.WriteString(v.Value(value))
+	}
+	buf.WriteString("|")
+	return in.writeLine(buf.String())
+}
+
+// Exec sets the variable
+func (c *Set) Exec(in *Interpreter) error {
+	return in.set(c.Var, c.Value)
+}
+
+// Exec starts a clock cycle
+func (c *Tick) Exec(in *Interpreter) error {
+	in.tick = true
+	return nil
+}
+
+// Exec finishes a clock cycle by executing an instruction
+func (c *Tock) Exec(in *Interpreter) error {
+	in.tick = false
+	in.time++
+	return in.Machine.Step()
+}
+
+// Exec executes an instruction
+func (c *TickTock) Exec(in *Interpreter) error {
+	in.tick = false
+	in.time++
+	return in.Machine.Step()
+}
+
+// Exec prints the message
+func (c *Echo) Exec(in *Interpreter) error {
+	if in.Echo == nil {
+		return nil
+	}
+	_, err := fmt.Fprintln(in.Echo, c.Text)
+	return err
+}
+
+// Exec runs the body Count times
+func (c *Repeat) Exec(in *Interpreter) error {
+	for i := 0; i < c.Count; i++ {
+		err := in.Run(c.Body)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Exec runs the body while the condition holds
+func (c *While) Exec(in *Interpreter) error {
+	for {
+		value, err := in.get(c.Cond.Var)
+		if err != nil {
+			return err
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid condition on %s", c.Cond.Var)
+		}
+		if !c.Cond.eval(int16(n)) {
+			return nil
+		}
+		err = in.Run(c.Body)
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package tst_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wongak/nand2tetris/pkg/hack/tst"
+)
+
+const multTst = `// test script for mult.asm
+load Mult.asm,
+output-file Mult.out,
+compare-to Mult.cmp,
+output-list RAM[0]%D2.6.2 RAM[1]%D2.6.2 RAM[2]%D2.6.2;
+
+set RAM[0] 0,
+set RAM[1] 0,
+set RAM[2] -1;
+repeat 20 {
+  ticktock;
+}
+output;
+
+set PC 0,
+set RAM[0] 3,
+set RAM[1] 1,
+set RAM[2] -1;
+repeat 100 {
+  ticktock;
+}
+output;
+
+/* runs until the program halts */
+set PC 0,
+set RAM[0] 6,
+set RAM[1] 7,
+set RAM[2] -1;
+while PC < 21 {
+  ticktock;
+}
+output;
+`
+
+const multCmp = `|  RAM[0]  |  RAM[1]  |  RAM[2]  |
+|       0  |       0  |       0  |
+|       3  |       1  |       3  |
+|       6  |       7  |      42  |
+`
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func multAsm(t *testing.T) string {
+	src, err := os.ReadFile("../../../mult.asm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(src)
+}
+
+func TestRunMult(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"Mult.asm": multAsm(t),
+		"Mult.tst": multTst,
+		"Mult.cmp": multCmp,
+	})
+	err := tst.RunFile(filepath.Join(dir, "Mult.tst"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out, err := os.ReadFile(filepath.Join(dir, "Mult.out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != multCmp {
+		t.Errorf("unexpected output. expect\n%s\ngot\n%s", multCmp, out)
+	}
+}
+
+func TestRunMultComparisonFailure(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"Mult.asm": multAsm(t),
+		"Mult.tst": multTst,
+		"Mult.cmp": strings.Replace(multCmp, "42", "41", 1),
+	})
+	err := tst.RunFile(filepath.Join(dir, "Mult.tst"))
+	if err == nil {
+		t.Fatal("expect comparison failure")
+	}
+	cerr, ok := err.(*tst.CompareError)
+	if !ok {
+		t.Fatalf("expect compare error, got %v", err)
+	}
+	if cerr.Line != 4 {
+		t.Errorf("expect failure on line 4, got %d", cerr.Line)
+	}
+	if !strings.Contains(err.Error(), "^") {
+		t.Errorf("expect diff marker in error, got %v", err)
+	}
+}
+
+func TestRunFileParseError(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"Bad.tst": "load Mult.asm\nfoo;\n",
+	})
+	fileName := filepath.Join(dir, "Bad.tst")
+	err := tst.RunFile(fileName)
+	if err == nil {
+		t.Fatal("expect parse error")
+	}
+	if !strings.HasPrefix(err.Error(), fileName+": ") || strings.Count(err.Error(), "Bad.tst") != 1 {
+		t.Errorf("expect error prefixed once with the file name, got %v", err)
+	}
+}
+
+func TestOutputFormat(t *testing.T) {
+	p := tst.NewParser(strings.NewReader(`
+output-list RAM[0]%D1.6.1 A%B1.16.1 D%X2.4.2 time%S1.4.1;
+set RAM[0] -12, set A %X00FF, set D 255;
+output;
+	`))
+	err := p.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := writeFiles(t, map[string]string{
+		"Format.cmp": `| RAM[0] |        A         |   D    | time |
+|    -12 | 0000000011111111 |  00FF  | 0    |
+`,
+	})
+	interp := tst.NewInterpreter(dir)
+	defer interp.Close()
+	cmds := append([]tst.Command{&tst.CompareTo{File: "Format.cmp"}}, p.Tree()...)
+	err = interp.Run(cmds)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestParseError(t *testing.T) {
+	p := tst.NewParser(strings.NewReader(`
+load Mult.asm,
+repeat 3 {
+  ticktock;
+`))
+	err := p.Run()
+	if err == nil {
+		t.Fatal("expect error on unterminated block")
+	}
+	p = tst.NewParser(strings.NewReader(`
+load Mult.asm,
+set RAM[0] abc;
+`))
+	err = p.Run()
+	if err == nil {
+		t.Fatal("expect error on invalid value")
+	}
+	perr, ok := err.(*tst.ParseError)
+	if !ok || perr.Line != 3 {
+		t.Errorf("expect parse error on line 3, got %v", err)
+	}
+}
@@ -0,0 +1,276 @@
+package tst
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// Token is a lexical token of a test script
+type Token int
+
+const (
+	ILLEGAL Token = iota
+	EOF
+	WS
+	COMMENT
+
+	VALUE
+	STRING
+
+	// command terminators
+	COMMA
+	SEMICOLON
+	BANG
+
+	LBRACE
+	RBRACE
+)
+
+func (t Token) String() string {
+	switch t {
+	case ILLEGAL:
+		return "ILLEGAL"
+	case EOF:
+		return "EOF"
+	case WS:
+		return "WS"
+	case COMMENT:
+		return "COMMENT"
+	case VALUE:
+		return "VALUE"
+	case STRING:
+		return "STRING"
+	case COMMA:
+		return "COMMA"
+	case SEMICOLON:
+		return "SEMICOLON"
+	case BANG:
+		return "BANG"
+	case LBRACE:
+		return "LBRACE"
+	case RBRACE:
+		return "RBRACE"
+	default:
+		return "unknown token"
+	}
+}
+
+func isTerminator(tok Token) bool {
+	return tok == COMMA || tok == SEMICOLON || tok == BANG
+}
+
+var eof = rune(0)
+
+func isWhitespace(ch rune) bool {
+	return ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r'
+}
+
+// returns true if the character ends a value
+func isDelimiter(ch rune) bool {
+	return ch == eof || isWhitespace(ch) ||
+		ch == ',' || ch == ';' || ch == '!' ||
+		ch == '{' || ch == '}' || ch == '"'
+}
+
+// Scanner can scan tokens of a test script
+type Scanner struct {
+	r *bufio.Reader
+
+	line int
+	last rune
+}
+
+// NewScanner creates a new scanner, which reads from the given Reader r
+func NewScanner(r io.Reader) *Scanner {
+	return &Scanner{
+		r: bufio.NewReader(r),
+
+		line: 1,
+	}
+}
+
+func (s *Scanner) read() (rune, error) {
+	ch, _, err := s.r.ReadRune()
+	if err != nil {
+		if err == io.EOF {
+			s.last = eof
+			return eof, nil
+		}
+		return eof, err
+	}
+	if ch == '\n' {
+		s.line++
+	}
+	s.last = ch
+	return ch, nil
+}
+
+func (s *Scanner) unread() error {
+	if s.last == eof {
+		return nil
+	}
+	if s.last == '\n' {
+		s.line--
+	}
+	return s.r.UnreadRune()
+}
+
+// Line returns the current line of the scanner, starting at 1
+func (s *Scanner) Line() int {
+	return s.line
+}
+
+// Scan returns the next token
+func (s *Scanner) Scan() (tok Token, lit string, err error) {
+	ch, err := s.read()
+	if err != nil {
+		return ILLEGAL, "", err
+	}
+
+	switch {
+	case ch == eof:
+		return EOF, "", nil
+	case isWhitespace(ch):
+		err := s.unread()
+		if err != nil {
+			return ILLEGAL, "", err
+		}
+		return s.scanWhitespace()
+	case ch == ',':
+		return COMMA, ",", nil
+	case ch == ';':
+		return SEMICOLON, ";", nil
+	case ch == '!':
+		return BANG, "!", nil
+	case ch == '{':
+		return LBRACE, "{", nil
+	case ch == '}':
+		return RBRACE, "}", nil
+	case ch == '"':
+		return s.scanString()
+	case ch == '/':
+		next, err := s.read()
+		if err != nil {
+			return ILLEGAL, "", err
+		}
+		if next == '/' {
+			return s.scanLineComment()
+		}
+		if next == '*' {
+			return s.scanBlockComment()
+		}
+		err = s.unread()
+		if err != nil {
+			return ILLEGAL, "", err
+		}
+		tok, lit, err := s.scanValue()
+		return tok, "/" + lit, err
+	}
+
+	err = s.unread()
+	if err != nil {
+		return ILLEGAL, "", err
+	}
+	return s.scanValue()
+}
+
+func (s *Scanner) scanWhitespace() (tok Token, lit string, err error) {
+	var buf bytes.Buffer
+	for {
+		if ch, err := s.read(); err != nil {
+			return ILLEGAL, "", err
+		} else if ch == eof {
+			break
+		} else if !isWhitespace(ch) {
+			err = s.unread()
+			if err != nil {
+				return ILLEGAL, "", err
+			}
+			break
+		} else {
+			buf.WriteRune(ch)
+		}
+	}
+
+	return WS, buf.String(), nil
+}
+
+func (s *Scanner) scanValue() (tok Token, lit string, err error) {
+	var buf bytes.Buffer
+	for {
+		if ch, err := s.read(); err != nil {
+			return ILLEGAL, "", err
+		} else if isDelimiter(ch) {
+			err = s.unread()
+			if err != nil {
+				return ILLEGAL, "", err
+			}
+			break
+		} else {
+			buf.WriteRune(ch)
+		}
+	}
+
+	return VALUE, buf.String(), nil
+}
+
+func (s *Scanner) scanString() (tok Token, lit string, err error) {
+	var buf bytes.Buffer
+	for {
+		if ch, err := s.read(); err != nil {
+			return ILLEGAL, "", err
+		} else if ch == eof || ch == '\n' {
+			return ILLEGAL, buf.String(), fmt.Errorf("unterminated string on line %d", s.line)
+		} else if ch == '"' {
+			break
+		} else {
+			buf.WriteRune(ch)
+		}
+	}
+
+	return STRING, buf.String(), nil
+}
+
+func (s *Scanner) scanLineComment() (tok Token, lit string, err error) {
+	var buf bytes.Buffer
+	for {
+		if ch, err := s.read(); err != nil {
+			return ILLEGAL, "", err
+		} else if ch == eof {
+			break
+		} else if ch == '\n' {
+			err = s.unread()
+			if err != nil {
+				return ILLEGAL, "", err
+			}
+			break
+		} else {
+			buf.WriteRune(ch)
+		}
+	}
+
+	return COMMENT, buf.String(), nil
+}
+
+func (s *Scanner) scanBlockComment() (tok Token, lit string, err error) {
+	var buf bytes.Buffer
+	star := false
+	for {
+		ch, err := s.read()
+		if err != nil {
+			return ILLEGAL, "", err
+		}
+		if ch == eof {
+			return ILLEGAL, buf.String(), fmt.Errorf("unterminated comment on line %d", s.line)
+		}
+		if star && ch == '/' {
+			break
+		}
+		star = ch == '*'
+		buf.WriteRune(ch)
+	}
+
+	return COMMENT, buf.String(), nil
+}
@@ -0,0 +1,157 @@
+package tst
+
+import (
+	"fmt"
+	"io"
+)
+
+// Parser is a parser for test scripts
+type Parser struct {
+	s   *Scanner
+	buf struct {
+		tok         Token
+		lit         string
+		line        int
+		isUnscanned bool
+	}
+
+	tree []Command
+}
+
+// ParseError is returned by Run on invalid scripts
+type ParseError struct {
+	Line int
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("parse error on line %d: %v", e.Line, e.Err)
+}
+
+// NewParser creates a new parser on the given Reader r
+func NewParser(r io.Reader) *Parser {
+	return &Parser{
+		s: NewScanner(r),
+
+		tree: make([]Command, 0),
+	}
+}
+
+func (p *Parser) scan() (tok Token, lit string, err error) {
+	if p.buf.isUnscanned {
+		p.buf.isUnscanned = false
+		return p.buf.tok, p.buf.lit, nil
+	}
+
+	line := p.s.Line()
+	tok, lit, err = p.s.Scan()
+	if err != nil {
+		return ILLEGAL, "", err
+	}
+
+	p.buf.line = line
+	p.buf.tok = tok
+	p.buf.lit = lit
+
+	return
+}
+
+func (p *Parser) unscan() {
+	p.buf.isUnscanned = true
+}
+
+// scanIgnore ignores whitespace and comments
+func (p *Parser) scanIgnore() (tok Token, lit string, err error) {
+	for {
+		tok, lit, err = p.scan()
+		if err != nil {
+			return ILLEGAL, "", err
+		}
+		if tok == WS || tok == COMMENT {
+			continue
+		}
+		return
+	}
+}
+
+// Run parses the whole script
+func (p *Parser) Run() error {
+	tree, err := p.parseBlock(EOF)
+	if err != nil {
+		return &ParseError{
+			Line: p.buf.line,
+			Err:  err,
+		}
+	}
+	p.tree = tree
+	return nil
+}
+
+// Tree returns the parsed commands
+func (p *Parser) Tree() []Command {
+	return p.tree
+}
+
+// parseBlock parses commands until the given end token
+func (p *Parser) parseBlock(end Token) ([]Command, error) {
+	cmds := make([]Command, 0)
+	for {
+		tok, lit, err := p.scanIgnore()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case tok == end:
+			return cmds, nil
+		case tok == EOF:
+			return nil, fmt.Errorf("unexpected end of script. missing }")
+		case isTerminator(tok):
+			// empty command
+			continue
+		case tok != VALUE:
+			return nil, fmt.Errorf("invalid token %s (%s). expect command", tok, lit)
+		}
+		p.unscan()
+		cmd, err := p.parseCommand()
+		if err != nil {
+			return nil, err
+		}
+		cmds = append(cmds, cmd)
+	}
+}
+
+// parseCommand parses a command with its arguments up to the terminator
+// or a block
+func (p *Parser) parseCommand() (Command, error) {
+	tok, name, err := p.scanIgnore()
+	if err != nil {
+		return nil, err
+	}
+	line := p.buf.line
+	if tok != VALUE {
+		panic("internal error")
+	}
+
+	args := make([]string, 0)
+	for {
+		tok, lit, err := p.scanIgnore()
+		if err != nil {
+			return nil, err
+		}
+		if tok == VALUE || tok == STRING {
+			args = append(args, lit)
+			continue
+		}
+		if tok == LBRACE {
+			body, err := p.parseBlock(RBRACE)
+			if err != nil {
+				return nil, err
+			}
+			return newBlockCommand(line, name, args, body)
+		}
+		if isTerminator(tok) {
+			return newCommand(line, name, args)
+		}
+		return nil, fmt.Errorf("invalid token %s (%s) after %s. expect terminator", tok, lit, name)
+	}
+}
@@ -0,0 +1,66 @@
+package emulator
+
+import (
+	"fmt"
+
+	"github.com/wongak/nand2tetris/pkg/hack/vm/language"
+)
+
+// Program is a parsed VM program with resolved functions and labels
+type Program struct {
+	cmds      []language.Command
+	functions map[string]int
+	labels    map[string]int
+	statics   map[string]int
+}
+
+// NewProgram resolves the functions, labels and static variables of the
+// given commands. Commands of multiple files can be concatenated.
+func NewProgram(cmds []language.Command) (*Program, error) {
+	p := &Program{
+		cmds:      cmds,
+		functions: make(map[string]int),
+		labels:    make(map[string]int),
+		statics:   make(map[string]int),
+	}
+	for i, cmd := range cmds {
+		switch c := cmd.(type) {
+		case *language.Function:
+			if _, ok := p.functions[c.Name()]; ok {
+				return nil, fmt.Errorf("function %s already defined", c.Name())
+			}
+			p.functions[c.Name()] = i
+		case *language.Label:
+			label := scopedLabel(c.Function(), c.Name())
+			if _, ok := p.labels[label]; ok {
+				return nil, fmt.Errorf("label %s already defined", label)
+			}
+			p.labels[label] = i
+		case *language.MemoryAccess:
+			if c.Segment() != language.STATIC {
+				continue
+			}
+			static := staticName(c.File(), c.Index())
+			if _, ok := p.statics[static]; !ok {
+				p.statics[static] = StaticBase + len(p.statics)
+			}
+		}
+	}
+	if StaticBase+len(p.statics) > StackBase {
+		return nil, fmt.Errorf("too many static variables: %d", len(p.statics))
+	}
+	return p, nil
+}
+
+// Commands returns the commands of the program
+func (p *Program) Commands() []language.Command {
+	return p.cmds
+}
+
+func scopedLabel(function, label string) string {
+	return fmt.Sprintf("%s$%s", function, label)
+}
+
+func staticName(file string, index int) string {
+	return fmt.Sprintf("%s.%d", file, index)
+}
@@ -0,0 +1,298 @@
+package emulator
+
+import (
+	"fmt"
+
+	"github.com/wongak/nand2tetris/pkg/hack/vm/language"
+)
+
+const (
+	// MemorySize is the number of addressable words of the RAM
+	MemorySize = 0x8000
+
+	// SP is the address of the stack pointer
+	SP = 0
+	// LCL is the address of the local segment pointer
+	LCL = 1
+	// ARG is the address of the argument segment pointer
+	ARG = 2
+	// THIS is the address of the this segment pointer
+	THIS = 3
+	// THAT is the address of the that segment pointer
+	THAT = 4
+	// TempBase is the address of the temp segment
+	TempBase = 5
+	// StaticBase is the address of the first static variable
+	StaticBase = 16
+	// StackBase is the initial stack pointer
+	StackBase = 256
+)
+
+// VM interprets a VM program on the same memory layout as the
+// translated assembly
+type VM struct {
+	RAM [MemorySize]int16
+	// PC is the index of the next command in the program
+	PC int
+
+	program *Program
+	steps   int
+	halted  bool
+}
+
+// New creates a VM for the program. The stack pointer is not initialized
+// unless Bootstrap is called.
+func New(p *Program) *VM {
+	return &VM{
+		program: p,
+	}
+}
+
+// Bootstrap initializes the stack pointer and calls Sys.init
+func (vm *VM) Bootstrap() error {
+	vm.RAM[SP] = StackBase
+	vm.PC = len(vm.program.cmds)
+	return vm.call("Sys.init", 0)
+}
+
+// Steps returns the number of executed commands
+func (vm *VM) Steps() int {
+	return vm.steps
+}
+
+// Halted returns true if the program ran past its last command or
+// entered an infinite loop (label L; goto L)
+func (vm *VM) Halted() bool {
+	return vm.halted || vm.PC >= len(vm.program.cmds)
+}
+
+// Static returns the value of the static variable index of the file
+func (vm *VM) Static(file string, index int) int16 {
+	addr, ok := vm.program.statics[staticName(file, index)]
+	if !ok {
+		return 0
+	}
+	return vm.RAM[addr]
+}
+
+// Stack returns the content of the stack from the stack base to SP
+func (vm *VM) Stack() []int16 {
+	sp := int(vm.RAM[SP])
+	if sp < StackBase || sp > MemorySize {
+		return nil
+	}
+	return vm.RAM[StackBase:sp]
+}
+
+// Run executes commands until the program halts or maxSteps commands
+// were executed. It returns the number of executed commands.
+func (vm *VM) Run(maxSteps int) (int, error) {
+	i := 0
+	for ; i < maxSteps && !vm.Halted(); i++ {
+		if err := vm.Step(); err != nil {
+			return i, err
+		}
+	}
+	return i, nil
+}
+
+// Step executes a single command
+func (vm *VM) Step() error {
+	if vm.PC < 0 || vm.PC >= len(vm.program.cmds) {
+		return fmt.Errorf("program counter %d out of program", vm.PC)
+	}
+	cmd := vm.program.cmds[vm.PC]
+	vm.steps++
+	err := vm.exec(cmd)
+	if err != nil {
+		return fmt.Errorf("error executing %s (command %d): %v", cmd, vm.PC, err)
+	}
+	return nil
+}
+
+func (vm *VM) exec(cmd language.Command) error {
+	switch c := cmd.(type) {
+	case *language.MemoryAccess:
+		return vm.memoryAccess(c)
+	case *language.Arithmetic:
+		return vm.arithmetic(c)
+	case *language.Label:
+		vm.PC++
+		return nil
+	case *language.Goto:
+		target, err := vm.label(c.Function(), c.Label())
+		if err != nil {
+			return err
+		}
+		if target == vm.PC-1 {
+			vm.halted = true
+		}
+		vm.PC = target
+		return nil
+	case *language.IfGoto:
+		target, err := vm.label(c.Function(), c.Label())
+		if err != nil {
+			return err
+		}
+		if vm.pop() != 0 {
+			vm.PC = target
+			return nil
+		}
+		vm.PC++
+		return nil
+	case *language.Function:
+		for i := 0; i < c.NumLocal(); i++ {
+			vm.push(0)
+		}
+		vm.PC++
+		return nil
+	case *language.Call:
+		vm.PC++
+		return vm.call(c.Name(), c.NumArgs())
+	case *language.Return:
+		return vm.ret()
+	}
+	return fmt.Errorf("unsupported command %T", cmd)
+}
+
+// mem returns the RAM address for a value, using the lower 15 bits
+// like the Hack CPU
+func mem(addr int16) int {
+	return int(uint16(addr) & (MemorySize - 1))
+}
+
+func (vm *VM) push(value int16) {
+	vm.RAM[mem(vm.RAM[SP])] = value
+	vm.RAM[SP]++
+}
+
+func (vm *VM) pop() int16 {
+	vm.RAM[SP]--
+	return vm.RAM[mem(vm.RAM[SP])]
+}
+
+func (vm *VM) label(function, label string) (int, error) {
+	target, ok := vm.program.labels[scopedLabel(function, label)]
+	if !ok {
+		return 0, fmt.Errorf("undefined label %s", label)
+	}
+	return target, nil
+}
+
+// address returns the RAM address of the segment index
+func (vm *VM) address(m *language.MemoryAccess) (int, error) {
+	index := m.Index()
+	switch m.Segment() {
+	case language.LCL:
+		return int(vm.RAM[LCL]) + index, nil
+	case language.ARG:
+		return int(vm.RAM[ARG]) + index, nil
+	case language.THIS:
+		return int(vm.RAM[THIS]) + index, nil
+	case language.THAT:
+		return int(vm.RAM[THAT]) + index, nil
+	case language.TEMP:
+		if index > 7 {
+			return 0, fmt.Errorf("temp index %d out of range", index)
+		}
+		return TempBase + index, nil
+	case language.POINTER:
+		return THIS + index, nil
+	case language.STATIC:
+		return vm.program.statics[staticName(m.File(), index)], nil
+	}
+	return 0, fmt.Errorf("invalid segment %s", m.Segment())
+}
+
+func (vm *VM) memoryAccess(m *language.MemoryAccess) error {
+	vm.PC++
+	if m.Segment() == language.CONSTANT {
+		vm.push(int16(m.Index()))
+		return nil
+	}
+	addr, err := vm.address(m)
+	if err != nil {
+		return err
+	}
+	if addr < 0 || addr >= MemorySize {
+		return fmt.Errorf("address %d out of memory", addr)
+	}
+	if m.Command() == language.PUSH {
+		vm.push(vm.RAM[addr])
+		return nil
+	}
+	vm.RAM[addr] = vm.pop()
+	return nil
+}
+
+func boolean(b bool) int16 {
+	if b {
+		return -1
+	}
+	return 0
+}
+
+func (vm *VM) arithmetic(a *language.Arithmetic) error {
+	vm.PC++
+	switch a.Command() {
+	case language.NEG:
+		vm.push(-vm.pop())
+		return nil
+	case language.NOT:
+		vm.push(^vm.pop())
+		return nil
+	}
+
+	y := vm.pop()
+	x := vm.pop()
+	switch a.Command() {
+	case language.ADD:
+		vm.push(x + y)
+	case language.SUB:
+		vm.push(x - y)
+	case language.AND:
+		vm.push(x & y)
+	case language.OR:
+		vm.push(x | y)
+	case language.EQ:
+		vm.push(boolean(x == y))
+	case language.GT:
+		vm.push(boolean(x > y))
+	case language.LT:
+		vm.push(boolean(x < y))
+	default:
+		return fmt.Errorf("invalid arithmetic command %s", a.Command())
+	}
+	return nil
+}
+
+// call pushes the frame of the caller and jumps to the function.
+// The PC must already point to the return address.
+func (vm *VM) call(name string, numArgs int) error {
+	target, ok := vm.program.functions[name]
+	if !ok {
+		return fmt.Errorf("undefined function %s", name)
+	}
+	vm.push(int16(vm.PC))
+	vm.push(vm.RAM[LCL])
+	vm.push(vm.RAM[ARG])
+	vm.push(vm.RAM[THIS])
+	vm.push(vm.RAM[THAT])
+	vm.RAM[ARG] = vm.RAM[SP] - 5 - int16(numArgs)
+	vm.RAM[LCL] = vm.RAM[SP]
+	vm.PC = target
+	return nil
+}
+
+func (vm *VM) ret() error {
+	frame := vm.RAM[LCL]
+	retAddr := vm.RAM[mem(frame-5)]
+	vm.RAM[mem(vm.RAM[ARG])] = vm.pop()
+	vm.RAM[SP] = vm.RAM[ARG] + 1
+	vm.RAM[THAT] = vm.RAM[mem(frame-1)]
+	vm.RAM[THIS] = vm.RAM[mem(frame-2)]
+	vm.RAM[ARG] = vm.RAM[mem(frame-3)]
+	vm.RAM[LCL] = vm.RAM[mem(frame-4)]
+	vm.PC = int(retAddr)
+	return nil
+}
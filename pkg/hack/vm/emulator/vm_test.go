@@ -0,0 +1,254 @@
+package emulator_test
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+	"testing"
+
+	asm "github.com/wongak/nand2tetris/pkg/hack/assembly/language"
+	"github.com/wongak/nand2tetris/pkg/hack/cpu"
+	"github.com/wongak/nand2tetris/pkg/hack/vm/emulator"
+	"github.com/wongak/nand2tetris/pkg/hack/vm/language"
+)
+
+// parse parses the files in order of their names with a shared symbol table
+func parse(t *testing.T, files map[string]string) (*language.SymbolTable, []language.Command) {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	table := language.NewSymbolTable()
+	cmds := make([]language.Command, 0)
+	for _, name := range names {
+		p := language.NewParser(strings.NewReader(files[name]))
+		err := p.Run(table, name)
+		if err != nil {
+			t.Fatalf("error on parse %s: %v", name, err)
+		}
+		cmds = append(cmds, p.Tree()...)
+	}
+	return table, cmds
+}
+
+func run(t *testing.T, files map[string]string, bootstrap bool) *emulator.VM {
+	_, cmds := parse(t, files)
+	p, err := emulator.NewProgram(cmds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vm := emulator.New(p)
+	if bootstrap {
+		err = vm.Bootstrap()
+		if err != nil {
+			t.Fatal(err)
+		}
+	} else {
+		vm.RAM[emulator.SP] = emulator.StackBase
+	}
+	_, err = vm.Run(100000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !vm.Halted() {
+		t.Fatal("expect program to halt")
+	}
+	return vm
+}
+
+func expectStack(t *testing.T, got []int16, expect ...int16) {
+	if len(got) != len(expect) {
+		t.Errorf("expect stack %v, got %v", expect, got)
+		return
+	}
+	for i := range expect {
+		if got[i] != expect[i] {
+			t.Errorf("expect stack %v, got %v", expect, got)
+			return
+		}
+	}
+}
+
+func TestArithmetic(t *testing.T) {
+	vm := run(t, map[string]string{"Test": `
+	push constant 7
+	push constant 8
+	add
+	push constant 20
+	sub
+	neg
+	push constant 3
+	push constant 3
+	eq
+	push constant 4
+	push constant 3
+	gt
+	push constant 4
+	push constant 3
+	lt
+	push constant 12
+	push constant 10
+	and
+	push constant 12
+	push constant 10
+	or
+	push constant 0
+	not
+	`}, false)
+	expectStack(t, vm.Stack(), 5, -1, -1, 0, 8, 14, -1)
+}
+
+func TestMemoryAccess(t *testing.T) {
+	vm := run(t, map[string]string{"Test": `
+	push constant 3000
+	pop pointer 0
+	push constant 4000
+	pop pointer 1
+	push constant 10
+	pop this 2
+	push constant 11
+	pop that 3
+	push constant 12
+	pop temp 6
+	push constant 13
+	pop static 5
+	push this 2
+	push that 3
+	push temp 6
+	push static 5
+	push pointer 1
+	`}, false)
+	expectStack(t, vm.Stack(), 10, 11, 12, 13, 4000)
+	if vm.RAM[3002] != 10 || vm.RAM[4003] != 11 || vm.RAM[11] != 12 {
+		t.Errorf("unexpected segment content")
+	}
+	if vm.Static("Test", 5) != 13 {
+		t.Errorf("expect static 13, got %d", vm.Static("Test", 5))
+	}
+}
+
+const fibonacci = `
+function Main.fibonacci 0
+	push argument 0
+	push constant 2
+	lt
+	if-goto IF_TRUE
+	goto IF_FALSE
+label IF_TRUE
+	push argument 0
+	return
+label IF_FALSE
+	push argument 0
+	push constant 2
+	sub
+	call Main.fibonacci 1
+	push argument 0
+	push constant 1
+	sub
+	call Main.fibonacci 1
+	add
+	return
+`
+
+const sys = `
+function Sys.init 0
+	push constant 4
+	call Main.fibonacci 1
+	pop static 0
+	push constant 10
+	call Main.fibonacci 1
+label WHILE
+	goto WHILE
+`
+
+func TestCallReturn(t *testing.T) {
+	vm := run(t, map[string]string{
+		"Main": fibonacci,
+		"Sys":  sys,
+	}, true)
+	if vm.Static("Sys", 0) != 3 {
+		t.Errorf("expect fibonacci(4) = 3, got %d", vm.Static("Sys", 0))
+	}
+	// return address, LCL, ARG, THIS, THAT of Sys.init followed by the result
+	stack := vm.Stack()
+	if len(stack) != 6 || stack[5] != 55 {
+		t.Errorf("expect fibonacci(10) = 55 on the stack, got %v", stack)
+	}
+}
+
+func TestUndefinedFunction(t *testing.T) {
+	_, cmds := parse(t, map[string]string{"Sys": `
+function Sys.init 0
+	call Main.main 0
+	return
+	`})
+	p, err := emulator.NewProgram(cmds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vm := emulator.New(p)
+	err = vm.Bootstrap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = vm.Run(100)
+	if err == nil || !strings.Contains(err.Error(), "undefined function Main.main") {
+		t.Errorf("expect undefined function error, got %v", err)
+	}
+}
+
+// TestTranslation compares the interpreted program with the translated
+// and emulated assembly
+func TestTranslation(t *testing.T) {
+	files := map[string]string{
+		"Main": fibonacci,
+		"Sys":  sys,
+	}
+	vm := run(t, files, true)
+
+	table, cmds := parse(t, files)
+	program := []asm.Command{
+		&asm.AInstruction{Address: "256"},
+		&asm.CInstruction{Dest: "D", Comp: "A", Jump: asm.NULL},
+		&asm.AInstruction{Address: "SP"},
+		&asm.CInstruction{Dest: "M", Comp: "D", Jump: asm.NULL},
+	}
+	cmds = append([]language.Command{language.NewCall("Sys.init", 0)}, cmds...)
+	for _, cmd := range cmds {
+		code, err := cmd.Translate(table)
+		if err != nil {
+			t.Fatal(err)
+		}
+		program = append(program, code...)
+	}
+	hack := bytes.NewBuffer(nil)
+	err := asm.Assemble(asm.NewSymbolTable(), program, hack)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := cpu.NewMachine()
+	err = m.Load(hack)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = m.Run(1000000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.Halted() {
+		t.Fatal("expect machine to halt")
+	}
+
+	for _, addr := range []int{emulator.SP, emulator.LCL, emulator.ARG, emulator.StaticBase} {
+		if m.RAM[addr] != vm.RAM[addr] {
+			t.Errorf("RAM[%d] differs. assembly %d, vm %d", addr, m.RAM[addr], vm.RAM[addr])
+		}
+	}
+	// the top of the stack holds the result, below is the frame of Sys.init
+	sp := vm.RAM[emulator.SP]
+	if m.RAM[sp-1] != vm.RAM[sp-1] {
+		t.Errorf("result differs. assembly %d, vm %d", m.RAM[sp-1], vm.RAM[sp-1])
+	}
+}
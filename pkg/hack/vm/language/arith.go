@@ -2,136 +2,176 @@ package language
 
 import (
 	"fmt"
-	"io"
-	"text/template"
+
+	asm "github.com/wongak/nand2tetris/pkg/hack/assembly/language"
 )
 
-const arithmeticOp = `// {{ .cmdLit }}
-	@SP
-	M=M-1 // SP--
-	A=M
-	D=M // D=*SP
-	
-	@SP
-	M=M-1 // SP--
-	A=M
-	{{ .operation }}
-	
-	M=D
-	@SP
-	M=M+1 // SP++
-`
-
-var arithmeticOpTmpl *template.Template
-
-const arithmeticSingleOp = `// {{ .cmdLit }}
-	@SP
-	M=M-1 // SP--
-	A=M
-	D=M // D=*SP
-
-	{{ .operation }}
-
-	M=D
-	@SP
-	M=M+1 // SP++
-`
-
-var arithmeticSingleOpTmpl *template.Template
-
-const logicalComp = `// {{ .cmdLit }}
-	@SP
-	M=M-1 // SP--
-	A=M
-	D=M // D=*SP
-	
-	@SP
-	M=M-1 // SP--
-	A=M
-	D=D-M // D=D-*SP
-	
-	@R13
-	M=-1 // true
-	@{{ .labelSet }}
-	D;{{ .comp }} // jump if true
-	@R13
-	M=0 // false
-	
-({{ .labelSet }})
-	@R13
-	D=M // D=result
-	@SP
-	A=M
-	M=D
-	@SP
-	M=M+1
-`
-
-var logicalCompTmpl *template.Template
-
-func init() {
-	arithmeticOpTmpl = template.Must(template.New("arithmeticOp").Parse(arithmeticOp))
-	arithmeticSingleOpTmpl = template.Must(template.New("arithmeticSingleOp").Parse(arithmeticSingleOp))
-	logicalCompTmpl = template.Must(template.New("logicalComp").Parse(logicalComp))
+// operations maps the arithmetic/logical commands to the computation of
+// D from the top of the stack in D and, for binary operations, the
+// second value in M
+var operations = map[Token]string{
+	ADD: "D+M",
+	SUB: "M-D",
+	NEG: "-D",
+	AND: "D&M",
+	OR:  "D|M",
+	NOT: "!D",
+}
+
+// compJumps maps the comparisons to the jump on x - y
+var compJumps = map[Token]asm.Token{
+	EQ: asm.JEQ,
+	GT: asm.JGT,
+	LT: asm.JLT,
 }
 
 // Arithmetic represents an arithmetic/logical command
 type Arithmetic struct {
+	node
+
 	cmd Token
 	lit string
 
 	file *File
 }
 
+// NewArithmetic creates an arithmetic/logical command
+func NewArithmetic(cmd Token, file *File) *Arithmetic {
+	return &Arithmetic{
+		cmd: cmd,
+		lit: cmd.Literal(),
+
+		file: file,
+	}
+}
+
 // String implements the Stringer
 func (a *Arithmetic) String() string {
 	return fmt.Sprintf("%s", a.cmd)
 }
 
+// Code returns the VM code of the command
+func (a *Arithmetic) Code() string {
+	return a.lit
+}
+
+// Command returns the arithmetic/logical command
+func (a *Arithmetic) Command() Token {
+	return a.cmd
+}
+
+// SourceFile returns the file the command was parsed from
+func (a *Arithmetic) SourceFile() *File {
+	return a.file
+}
+
 // Translate implementing the Command
-func (a *Arithmetic) Translate(t *SymbolTable, wr io.Writer) error {
+func (a *Arithmetic) Translate(t *SymbolTable) ([]asm.Command, error) {
 	ft, err := t.FileTable(a.file.name)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	data := map[string]string{
-		"cmdLit": a.lit,
-	}
-	tmpl := arithmeticOpTmpl
+	b := &builder{}
+	b.comment(a.lit)
 	switch a.cmd {
-	case ADD:
-		data["operation"] = "D=D+M"
-	case SUB:
-		data["operation"] = "D=M-D"
-	case NEG:
-		tmpl = arithmeticSingleOpTmpl
-		data["operation"] = "D=-D"
-	case AND:
-		data["operation"] = "D=D&M"
-	case OR:
-		data["operation"] = "D=D|M"
-	case NOT:
-		data["operation"] = "D=!D"
-		tmpl = arithmeticSingleOpTmpl
-	case EQ:
-		data["labelSet"] = ft.Condition()
-		tmpl = logicalCompTmpl
-		data["comp"] = "JEQ" // true if pop1 - pop2 = 0
-	case GT:
-		data["labelSet"] = ft.Condition()
-		tmpl = logicalCompTmpl
-		data["comp"] = "JLT" // true if pop1 - pop2 < 0
-	case LT:
-		data["labelSet"] = ft.Condition()
-		tmpl = logicalCompTmpl
-		data["comp"] = "JGT"
+	case EQ, GT, LT:
+		if t.sharedRoutines {
+			returnLabel := ft.Condition()
+			b.at(returnLabel)
+			b.c("D", "A")
+			b.at("R15")
+			b.c("M", "D")
+			b.note("R15 = return address")
+			b.goTo(compRoutineLabel(a.cmd))
+			b.label(returnLabel)
+			break
+		}
+		b.compare(a.cmd, ft.Condition())
+	case NEG, NOT:
+		b.popD()
+		b.c("D", operations[a.cmd])
+		b.c("M", "D")
+		b.at("SP")
+		b.c("M", "M+1")
+		b.note("SP++")
+	default:
+		b.popD()
+		b.at("SP")
+		b.c("M", "M-1")
+		b.note("SP--")
+		b.c("A", "M")
+		b.c("D", operations[a.cmd])
+		b.c("M", "D")
+		b.at("SP")
+		b.c("M", "M+1")
+		b.note("SP++")
 	}
-	err = tmpl.Execute(wr, data)
-	if err != nil {
-		return err
+	return b.cmds, nil
+}
+
+// compare compares x (second on the stack) with y (top of the stack)
+// and replaces x with the result. The labels of the comparison are
+// prefixed with label.
+//
+// x - y overflows if x and y have different signs, so ordering
+// comparisons decide on the signs alone in that case.
+func (b *builder) compare(cmd Token, label string) {
+	b.at("SP")
+	b.c("AM", "M-1")
+	b.note("SP--")
+	b.c("D", "M")
+	b.at("R13")
+	b.c("M", "D")
+	b.note("R13 = y")
+	b.at("SP")
+	b.c("A", "M-1")
+	b.c("D", "M")
+	b.note("D = x")
+	if cmd != EQ {
+		b.at(label + ".xneg")
+		b.jump("D", asm.JLT)
+		b.at("R13")
+		b.c("D", "M")
+		b.at(label + ".diff")
+		b.jump("D", asm.JGE)
+		b.note("x, y >= 0")
+		b.c("D", "1")
+		b.note("x >= 0 > y")
+		b.goTo(label + ".cmp")
+		b.label(label + ".xneg")
+		b.at("R13")
+		b.c("D", "M")
+		b.at(label + ".diff")
+		b.jump("D", asm.JLT)
+		b.note("x, y < 0")
+		b.c("D", "-1")
+		b.note("x < 0 <= y")
+		b.goTo(label + ".cmp")
+		b.label(label + ".diff")
+		b.at("SP")
+		b.c("A", "M-1")
+		b.c("D", "M")
+		b.note("D = x")
+	}
+	b.at("R13")
+	b.c("D", "D-M")
+	b.note("D = x - y")
+	if cmd != EQ {
+		b.label(label + ".cmp")
 	}
-	return nil
+	b.at(label + ".true")
+	b.jump("D", compJumps[cmd])
+	b.c("D", "0")
+	b.note("false")
+	b.goTo(label + ".end")
+	b.label(label + ".true")
+	b.c("D", "-1")
+	b.note("true")
+	b.label(label + ".end")
+	b.at("SP")
+	b.c("A", "M-1")
+	b.c("M", "D")
 }
 
 func parseArithmetic(p *Parser, ctx ParserContext) (ParserContext, stateFunc) {
@@ -143,8 +183,9 @@ func parseArithmetic(p *Parser, ctx ParserContext) (ParserContext, stateFunc) {
 		return ctx, parseError(fmt.Errorf("invalid token %s (%s). epxect arithmetic/logical cmd", tok, lit))
 	}
 	cmd := &Arithmetic{
-		cmd: tok,
-		lit: lit,
+		node: node{pos: ctx.pos},
+		cmd:  tok,
+		lit:  lit,
 
 		file: ctx.file,
 	}
@@ -0,0 +1,81 @@
+package language
+
+import (
+	"strconv"
+
+	asm "github.com/wongak/nand2tetris/pkg/hack/assembly/language"
+)
+
+// builder collects the assembly instructions of translated commands
+type builder struct {
+	cmds []asm.Command
+}
+
+func (b *builder) comment(text string) {
+	b.cmds = append(b.cmds, &asm.Comment{Text: text})
+}
+
+// note sets the trailing comment of the last C-instruction
+func (b *builder) note(text string) {
+	if c, ok := b.cmds[len(b.cmds)-1].(*asm.CInstruction); ok {
+		c.Comment = text
+	}
+}
+
+func (b *builder) label(name string) {
+	b.cmds = append(b.cmds, &asm.Label{Name: name})
+}
+
+// at writes the A-instruction @address
+func (b *builder) at(address string) {
+	b.cmds = append(b.cmds, &asm.AInstruction{Address: address})
+}
+
+// atInt writes the A-instruction loading the constant i
+func (b *builder) atInt(i int) {
+	b.at(strconv.Itoa(i))
+}
+
+// c writes the C-instruction dest=comp
+func (b *builder) c(dest, comp string) {
+	b.cmds = append(b.cmds, &asm.CInstruction{
+		Dest: dest,
+		Comp: comp,
+		Jump: asm.NULL,
+	})
+}
+
+// jump writes the C-instruction comp;jump
+func (b *builder) jump(comp string, jump asm.Token) {
+	b.cmds = append(b.cmds, &asm.CInstruction{
+		Comp: comp,
+		Jump: jump,
+	})
+}
+
+// goTo jumps unconditionally to the label
+func (b *builder) goTo(label string) {
+	b.at(label)
+	b.jump("0", asm.JMP)
+}
+
+// pushD pushes D onto the stack
+func (b *builder) pushD() {
+	b.at("SP")
+	b.c("A", "M")
+	b.c("M", "D")
+	b.note("*SP = D")
+	b.at("SP")
+	b.c("M", "M+1")
+	b.note("SP++")
+}
+
+// popD pops the top of the stack into D
+func (b *builder) popD() {
+	b.at("SP")
+	b.c("M", "M-1")
+	b.note("SP--")
+	b.c("A", "M")
+	b.c("D", "M")
+	b.note("D = *SP")
+}
@@ -2,55 +2,58 @@ package language
 
 import (
 	"fmt"
-	"io"
-	"text/template"
-)
-
-const labelAsm = `// {{ .cmdLit }} {{ .name }}
-({{ .label }})
-`
 
-var labelAsmTmpl *template.Template
-
-func init() {
-	labelAsmTmpl = template.Must(template.New("labelAsm").Parse(labelAsm))
-}
-
-func init() {
-	labelAsmTmpl = template.Must(template.New("labelAsm").Parse(labelAsm))
-}
+	asm "github.com/wongak/nand2tetris/pkg/hack/assembly/language"
+)
 
 // Label represents the label command
 type Label struct {
+	node
+
 	name string
 	lit  string
 
 	function *Function
 }
 
+// NewLabel creates a label command scoped to the function
+func NewLabel(name string, function *Function) *Label {
+	return &Label{
+		name: name,
+		lit:  LABEL.Literal(),
+
+		function: function,
+	}
+}
+
 // String implementing the Stringer
 func (l *Label) String() string {
 	return fmt.Sprintf("%s %s", LABEL, l.name)
 }
 
-// Translate generates assembly code for the label command
-func (l *Label) Translate(t *SymbolTable, wr io.Writer) error {
-	var ft *functionTable
-	if l.function == nil {
-		ft = t.FunctionTable("")
-	} else {
-		ft = t.FunctionTable(l.function.name)
-	}
+// Code returns the VM code of the command
+func (l *Label) Code() string {
+	return fmt.Sprintf("%s %s", l.lit, l.name)
+}
 
-	data := map[string]string{
-		"cmdLit": l.lit,
-		"name":   l.name,
-		"label":  ft.Label(l.name),
-	}
+// Name returns the name of the label
+func (l *Label) Name() string {
+	return l.name
+}
 
-	err := labelAsmTmpl.Execute(wr, data)
+// Function returns the name of the function the label is scoped to
+func (l *Label) Function() string {
+	return functionName(l.function)
+}
 
-	return err
+// Translate generates assembly code for the label command
+func (l *Label) Translate(t *SymbolTable) ([]asm.Command, error) {
+	ft := t.FunctionTable(functionName(l.function))
+
+	b := &builder{}
+	b.comment(l.Code())
+	b.label(ft.Label(l.name))
+	return b.cmds, nil
 }
 
 func parseLabel(p *Parser, ctx ParserContext) (ParserContext, stateFunc) {
@@ -62,6 +65,7 @@ func parseLabel(p *Parser, ctx ParserContext) (ParserContext, stateFunc) {
 		panic("internal error")
 	}
 	l := &Label{
+		node:     node{pos: ctx.pos},
 		lit:      lit,
 		function: ctx.function,
 	}
@@ -70,7 +74,7 @@ func parseLabel(p *Parser, ctx ParserContext) (ParserContext, stateFunc) {
 
 func parseLabelName(l *Label) stateFunc {
 	return func(p *Parser, ctx ParserContext) (ParserContext, stateFunc) {
-		tok, lit, err := p.scanIgnore()
+		tok, lit, err := p.scanArg("name", l.lit)
 		if err != nil {
 			return ctx, parseError(err)
 		}
@@ -82,54 +86,56 @@ func parseLabelName(l *Label) stateFunc {
 	}
 }
 
-const ifGotoAsm = `// {{ .cmdLit }} {{ .label }}
-	// pop
-	@SP
-	M=M-1 // SP--
-	A=M
-	D=M // D=*SP
-
-	@{{ .jumpLabel }}
-	D;JNE
-`
-
-var ifGotoAsmTmpl *template.Template
-
-func init() {
-	ifGotoAsmTmpl = template.Must(template.New("ifGotoAsm").Parse(ifGotoAsm))
-}
-
 // IfGoto implements the if-goto command
 type IfGoto struct {
+	node
+
 	lit   string
 	label string
 
 	function *Function
 }
 
+// NewIfGoto creates an if-goto command scoped to the function
+func NewIfGoto(label string, function *Function) *IfGoto {
+	return &IfGoto{
+		lit:   IFGOTO.Literal(),
+		label: label,
+
+		function: function,
+	}
+}
+
 // String implements Stringer
 func (g *IfGoto) String() string {
 	return fmt.Sprintf("%s %s", IFGOTO, g.label)
 }
 
-// Translate generates assembly code for if-goto
-func (g *IfGoto) Translate(t *SymbolTable, wr io.Writer) error {
-	var funcT *functionTable
-	if g.function == nil {
-		funcT = t.FunctionTable("")
-	} else {
-		funcT = t.FunctionTable(g.function.name)
-	}
+// Code returns the VM code of the command
+func (g *IfGoto) Code() string {
+	return fmt.Sprintf("%s %s", g.lit, g.label)
+}
 
-	data := map[string]string{
-		"cmdLit":    g.lit,
-		"label":     g.label,
-		"jumpLabel": funcT.Label(g.label),
-	}
+// Label returns the jump target label
+func (g *IfGoto) Label() string {
+	return g.label
+}
 
-	err := ifGotoAsmTmpl.Execute(wr, data)
+// Function returns the name of the function the jump is scoped to
+func (g *IfGoto) Function() string {
+	return functionName(g.function)
+}
+
+// Translate generates assembly code for if-goto
+func (g *IfGoto) Translate(t *SymbolTable) ([]asm.Command, error) {
+	ft := t.FunctionTable(functionName(g.function))
 
-	return err
+	b := &builder{}
+	b.comment(g.Code())
+	b.popD()
+	b.at(ft.Label(g.label))
+	b.jump("D", asm.JNE)
+	return b.cmds, nil
 }
 
 func parseIfGoto(p *Parser, ctx ParserContext) (ParserContext, stateFunc) {
@@ -141,64 +147,72 @@ func parseIfGoto(p *Parser, ctx ParserContext) (ParserContext, stateFunc) {
 		panic("internal error")
 	}
 	g := &IfGoto{
-		lit: lit,
+		node: node{pos: ctx.pos},
+		lit:  lit,
 
 		function: ctx.function,
 	}
 
-	tok, lit, err = p.scanIgnore()
+	tok, lit, err = p.scanArg("label", g.lit)
 	if err != nil {
 		return ctx, parseError(err)
 	}
 	if tok != VALUE {
-		return ctx, parseError(fmt.Errorf("invalid token %s (%s) after if-goto. expect label"))
+		return ctx, parseError(fmt.Errorf("invalid token %s (%s) after if-goto. expect label", tok, lit))
 	}
 	g.label = lit
 
 	return ctx, command(g)
 }
 
-const gotoAsm = `// {{ .cmdLit }} {{ .label }}
-	@{{ .jumpLabel }}
-	0;JMP
-`
-
-var gotoAsmTmpl *template.Template
-
-func init() {
-	gotoAsmTmpl = template.Must(template.New("gotoAsm").Parse(gotoAsm))
-}
-
 // Goto implements the goto command
 type Goto struct {
+	node
+
 	lit   string
 	label string
 
 	function *Function
 }
 
+// NewGoto creates a goto command scoped to the function
+func NewGoto(label string, function *Function) *Goto {
+	return &Goto{
+		lit:   GOTO.Literal(),
+		label: label,
+
+		function: function,
+	}
+}
+
 // String implements the Stringer
 func (g *Goto) String() string {
 	return fmt.Sprintf("%s %s", GOTO, g.label)
 }
 
-// Translate generates assembly code for goto
-func (g *Goto) Translate(t *SymbolTable, wr io.Writer) error {
-	var ft *functionTable
-	if g.function == nil {
-		ft = t.FunctionTable("")
-	} else {
-		ft = t.FunctionTable(g.function.name)
-	}
+// Code returns the VM code of the command
+func (g *Goto) Code() string {
+	return fmt.Sprintf("%s %s", g.lit, g.label)
+}
 
-	data := map[string]string{
-		"cmdLit":    g.lit,
-		"label":     g.label,
-		"jumpLabel": ft.Label(g.label),
-	}
+// Label returns the jump target label
+func (g *Goto) Label() string {
+	return g.label
+}
+
+// Function returns the name of the function the jump is scoped to
+func (g *Goto) Function() string {
+	return functionName(g.function)
+}
+
+// Translate generates assembly code for goto
+func (g *Goto) Translate(t *SymbolTable) ([]asm.Command, error) {
+	ft := t.FunctionTable(functionName(g.function))
 
-	err := gotoAsmTmpl.Execute(wr, data)
-	return err
+	b := &builder{}
+	b.comment(g.Code())
+	b.goTo(ft.Label(g.label))
+	return b.cmds, nil
 }
 
 func parseGoto(p *Parser, ctx ParserContext) (ParserContext, stateFunc) {
@@ -210,12 +224,13 @@ func parseGoto(p *Parser, ctx ParserContext) (ParserContext, stateFunc) {
 		panic("internal error")
 	}
 	g := &Goto{
-		lit: lit,
+		node: node{pos: ctx.pos},
+		lit:  lit,
 
 		function: ctx.function,
 	}
 
-	tok, lit, err = p.scanIgnore()
+	tok, lit, err = p.scanArg("label", g.lit)
 	if err != nil {
 		return ctx, parseError(err)
 	}
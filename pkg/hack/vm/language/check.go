@@ -0,0 +1,146 @@
+package language
+
+import "fmt"
+
+// scope names the function of a label for error messages
+func scope(function string) string {
+	if function == "" {
+		return "top level"
+	}
+	return "function " + function
+}
+
+// at locates a previous declaration for error messages
+func at(pos Pos) string {
+	if pos.Line == 0 {
+		return ""
+	}
+	return " at " + pos.String()
+}
+
+// UndefinedFunctionError reports a call to a function which is not
+// defined by the checked commands. The function may still be defined by
+// separately translated code, e.g. the Jack OS.
+type UndefinedFunctionError struct {
+	Name string
+}
+
+func (e *UndefinedFunctionError) Error() string {
+	return fmt.Sprintf("call to undefined function %s", e.Name)
+}
+
+// program holds the definitions of a VM program for the semantic check
+type program struct {
+	functions map[string]*Function
+	// labels holds the first declaration of each label by function
+	labels map[string]map[string]*Label
+	// numArgs holds the number of arguments used by each function, i.e.
+	// the highest argument index + 1
+	numArgs map[string]int
+	// calls holds the first call of each function
+	calls map[string]*Call
+	// undefined holds the reported undefined functions
+	undefined map[string]bool
+}
+
+func newProgram(cmds []Command) *program {
+	p := &program{
+		functions: make(map[string]*Function),
+		labels:    make(map[string]map[string]*Label),
+		numArgs:   make(map[string]int),
+		calls:     make(map[string]*Call),
+		undefined: make(map[string]bool),
+	}
+	var function string
+	for _, cmd := range cmds {
+		switch c := cmd.(type) {
+		case *Function:
+			function = c.Name()
+			if _, ok := p.functions[function]; !ok {
+				p.functions[function] = c
+			}
+		case *Label:
+			labels, ok := p.labels[c.Function()]
+			if !ok {
+				labels = make(map[string]*Label)
+				p.labels[c.Function()] = labels
+			}
+			if _, ok := labels[c.Name()]; !ok {
+				labels[c.Name()] = c
+			}
+		case *MemoryAccess:
+			if c.Segment() == ARG && c.Index()+1 > p.numArgs[function] {
+				p.numArgs[function] = c.Index() + 1
+			}
+		}
+	}
+	return p
+}
+
+func (p *program) label(function, name string) *Label {
+	return p.labels[function][name]
+}
+
+// Check checks the commands of a whole program for jumps to undefined
+// labels, duplicate labels and functions, calls to undefined functions
+// and calls with fewer arguments than the function uses or with
+// different numbers of arguments. The errors are returned as ErrorList
+// in command order. An undefined function is reported on its first call
+// only, as UndefinedFunctionError.
+func Check(cmds []Command) error {
+	p := newProgram(cmds)
+	errs := make(ErrorList, 0)
+	report := func(cmd Command, err error) {
+		errs = append(errs, &Error{Pos: cmd.Pos(), Err: err})
+	}
+
+	for _, cmd := range cmds {
+		switch c := cmd.(type) {
+		case *Function:
+			if p.functions[c.Name()] != c {
+				report(c, fmt.Errorf("function %s already defined%s", c.Name(), at(p.functions[c.Name()].Pos())))
+			}
+		case *Label:
+			if first := p.label(c.Function(), c.Name()); first != c {
+				report(c, fmt.Errorf("label %s already declared in %s%s", c.Name(), scope(c.Function()), at(first.Pos())))
+			}
+		case *Goto:
+			if p.label(c.Function(), c.Label()) == nil {
+				report(c, fmt.Errorf("undefined label %s in %s", c.Label(), scope(c.Function())))
+			}
+		case *IfGoto:
+			if p.label(c.Function(), c.Label()) == nil {
+				report(c, fmt.Errorf("undefined label %s in %s", c.Label(), scope(c.Function())))
+			}
+		case *Call:
+			p.checkCall(c, report)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (p *program) checkCall(c *Call, report func(Command, error)) {
+	if _, ok := p.functions[c.Name()]; !ok {
+		if !p.undefined[c.Name()] {
+			p.undefined[c.Name()] = true
+			report(c, &UndefinedFunctionError{Name: c.Name()})
+		}
+		return
+	}
+	if used := p.numArgs[c.Name()]; c.NumArgs() < used {
+		report(c, fmt.Errorf("call to %s with %d arguments, function uses argument %d", c.Name(), c.NumArgs(), used-1))
+		return
+	}
+	first, ok := p.calls[c.Name()]
+	if !ok {
+		p.calls[c.Name()] = c
+		return
+	}
+	if first.NumArgs() != c.NumArgs() {
+		report(c, fmt.Errorf("call to %s with %d arguments, called with %d%s", c.Name(), c.NumArgs(), first.NumArgs(), at(first.Pos())))
+	}
+}
@@ -0,0 +1,119 @@
+package language_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wongak/nand2tetris/pkg/hack/vm/language"
+)
+
+func parseFiles(t *testing.T, files ...string) []language.Command {
+	table := language.NewSymbolTable()
+	cmds := make([]language.Command, 0)
+	for i, src := range files {
+		name := string(rune('A' + i))
+		p := language.NewFileParser(strings.NewReader(src), name+".vm")
+		err := p.Run(table, name)
+		if err != nil {
+			t.Fatalf("unexpected error on parse: %v", err)
+		}
+		cmds = append(cmds, p.Tree()...)
+	}
+	return cmds
+}
+
+func TestCheck(t *testing.T) {
+	cmds := parseFiles(t, `
+function A.main 0
+label LOOP
+	push constant 1
+	call B.add 2
+	if-goto LOOP
+	goto END
+label LOOP
+	push constant 1
+	call B.add 1
+	call B.sub 2
+	call A.other 0
+	call B.sub 2
+	return
+function A.other 0
+	goto LOOP
+	push constant 0
+	return
+`, `
+function B.add 0
+	push argument 0
+	push argument 1
+	add
+	return
+function A.other 0
+	push constant 0
+	return
+`)
+	err := language.Check(cmds)
+	errs, ok := err.(language.ErrorList)
+	if !ok {
+		t.Fatalf("expect error list, got %v", err)
+	}
+	expect := []string{
+		"A.vm:7:2: undefined label END in function A.main",
+		"A.vm:8:1: label LOOP already declared in function A.main at A.vm:3:1",
+		"A.vm:10:2: call to B.add with 1 arguments, function uses argument 1",
+		"A.vm:11:2: call to undefined function B.sub",
+		"A.vm:16:2: undefined label LOOP in function A.other",
+		"B.vm:7:1: function A.other already defined at A.vm:15:1",
+	}
+	if len(errs) != len(expect) {
+		t.Fatalf("expect %d errors, got %d:\n%v", len(expect), len(errs), err)
+	}
+	for i, e := range expect {
+		if errs[i].Error() != e {
+			t.Errorf("expect error\n%s\ngot\n%s", e, errs[i])
+		}
+	}
+}
+
+func TestCheckArgumentCount(t *testing.T) {
+	cmds := parseFiles(t, `
+function A.main 0
+	push constant 1
+	push constant 2
+	call A.f 2
+	push constant 1
+	push constant 2
+	push constant 3
+	call A.f 3
+	return
+function A.f 0
+	push argument 0
+	return
+`)
+	err := language.Check(cmds)
+	if err == nil || err.Error() != "A.vm:9:2: call to A.f with 3 arguments, called with 2 at A.vm:5:2" {
+		t.Errorf("expect argument count mismatch, got %v", err)
+	}
+}
+
+func TestCheckValid(t *testing.T) {
+	cmds := parseFiles(t, `
+function A.main 0
+label LOOP
+	push constant 1
+	call A.f 1
+	if-goto LOOP
+	return
+function A.f 0
+label LOOP
+	push argument 0
+	return
+`)
+	err := language.Check(append(cmds, language.NewCall("A.main", 0)))
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	err = language.Check(append(cmds, language.NewCall("Sys.init", 0)))
+	if err == nil || err.Error() != "call to undefined function Sys.init" {
+		t.Errorf("expect undefined Sys.init without position, got %v", err)
+	}
+}
@@ -3,17 +3,58 @@ package language
 import (
 	"fmt"
 	"io"
+
+	asm "github.com/wongak/nand2tetris/pkg/hack/assembly/language"
 )
 
 // Command represents a valid command
 type Command interface {
 	fmt.Stringer
 
-	Translate(*SymbolTable, io.Writer) error
+	// Code returns the command as VM code
+	Code() string
+	// Pos returns the source position of the command. Commands which
+	// are not parsed have the zero position.
+	Pos() Pos
+	// Translate returns the assembly instructions of the command
+	Translate(*SymbolTable) ([]asm.Command, error)
+}
+
+// WriteCode writes the commands as VM code, one command per line
+func WriteCode(wr io.Writer, cmds []Command) error {
+	for _, cmd := range cmds {
+		indent := "\t"
+		if _, ok := cmd.(*Function); ok {
+			indent = ""
+		}
+		_, err := fmt.Fprintf(wr, "%s%s\n", indent, cmd.Code())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// node holds the source position of a command
+type node struct {
+	pos Pos
 }
 
-const endOp = `// END
-(END)
-	@END
-	0,JMP
-`
+// Pos returns the source position
+func (n node) Pos() Pos {
+	return n.pos
+}
+
+// setPos sets the source position
+func (n *node) setPos(pos Pos) {
+	n.pos = pos
+}
+
+// At sets the source position of a command which was not parsed, e.g.
+// compiled from Jack or created by the optimizer, and returns it
+func At(pos Pos, cmd Command) Command {
+	if n, ok := cmd.(interface{ setPos(Pos) }); ok {
+		n.setPos(pos)
+	}
+	return cmd
+}
@@ -1,5 +1,19 @@
 package language
 
+// File is the VM file commands were parsed from. Static variables
+// are scoped to the file.
 type File struct {
 	name string
 }
+
+// NewFile creates a file for commands which are not parsed, e.g.
+// generated by a compiler. The file must be registered in the symbol
+// table before translation.
+func NewFile(name string) *File {
+	return &File{name: name}
+}
+
+// Name returns the file name
+func (f *File) Name() string {
+	return f.name
+}
@@ -2,32 +2,15 @@ package language
 
 import (
 	"fmt"
-	"io"
 	"strconv"
-	"text/template"
-)
 
-const functionAsm = `// {{ .cmdLit }} {{ .numLocalLit }}
-({{ .functionLabel }})
-	// init local
-{{range .localVars}}
-	// push local 0 (arg {{ . }})
-	@SP
-	A=M
-	M=0
-	@SP
-	M=M+1
-{{- end }}
-`
-
-var functionAsmTmpl *template.Template
-
-func init() {
-	functionAsmTmpl = template.Must(template.New("functionAsm").Parse(functionAsm))
-}
+	asm "github.com/wongak/nand2tetris/pkg/hack/assembly/language"
+)
 
 // Function implements the VM function command
 type Function struct {
+	node
+
 	lit string
 
 	name string
@@ -36,30 +19,70 @@ type Function struct {
 	numLocalLit string
 }
 
+// NewFunction creates a function definition
+func NewFunction(name string, numLocal int) *Function {
+	return &Function{
+		lit: FUNCTION.Literal(),
+
+		name: name,
+
+		numLocal:    numLocal,
+		numLocalLit: strconv.FormatInt(int64(numLocal), 10),
+	}
+}
+
 // String implements the Stringer
 func (f *Function) String() string {
 	return fmt.Sprintf("%s %s %s", FUNCTION, f.name, f.numLocalLit)
 }
 
+// Code returns the VM code of the command
+func (f *Function) Code() string {
+	return fmt.Sprintf("%s %s %s", f.lit, f.name, f.numLocalLit)
+}
+
+// Name returns the function name
+func (f *Function) Name() string {
+	return f.name
+}
+
+// NumLocal returns the number of local variables
+func (f *Function) NumLocal() int {
+	return f.numLocal
+}
+
+// functionName returns the name of the function f or the empty
+// string for commands outside of a function
+func functionName(f *Function) string {
+	if f == nil {
+		return ""
+	}
+	return f.name
+}
+
 // Translate creates assembly for the function definition
-func (f *Function) Translate(t *SymbolTable, wr io.Writer) error {
+func (f *Function) Translate(t *SymbolTable) ([]asm.Command, error) {
 	ft, err := t.RegisterFunction(f.name)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	data := map[string]interface{}{
-		"cmdLit":        f.lit,
-		"numLocalLit":   f.numLocalLit,
-		"functionLabel": ft.FunctionLabel(),
+	b := &builder{}
+	b.comment(f.Code())
+	b.label(ft.FunctionLabel())
+	if f.numLocal > 0 {
+		b.comment("init local")
 	}
-	lcls := make([]int, f.numLocal)
 	for i := 0; i < f.numLocal; i++ {
-		lcls[i] = i
+		b.at("SP")
+		b.c("A", "M")
+		b.c("M", "0")
+		b.note(fmt.Sprintf("local %d = 0", i))
+		b.at("SP")
+		b.c("M", "M+1")
+		b.note("SP++")
 	}
-	data["localVars"] = lcls
-	err = functionAsmTmpl.Execute(wr, data)
-	return err
+	return b.cmds, nil
 }
 
 func parseFunction(p *Parser, ctx ParserContext) (ParserContext, stateFunc) {
@@ -71,10 +94,11 @@ func parseFunction(p *Parser, ctx ParserContext) (ParserContext, stateFunc) {
 		panic("internal error")
 	}
 	f := &Function{
-		lit: lit,
+		node: node{pos: ctx.pos},
+		lit:  lit,
 	}
 
-	tok, lit, err = p.scanIgnore()
+	tok, lit, err = p.scanArg("name", f.lit)
 	if err != nil {
 		return ctx, parseError(err)
 	}
@@ -83,7 +107,7 @@ func parseFunction(p *Parser, ctx ParserContext) (ParserContext, stateFunc) {
 	}
 	f.name = lit
 
-	tok, lit, err = p.scanIgnore()
+	tok, lit, err = p.scanArg("number of local vars", f.lit+" "+f.name)
 	if err != nil {
 		return ctx, parseError(err)
 	}
@@ -101,74 +125,10 @@ func parseFunction(p *Parser, ctx ParserContext) (ParserContext, stateFunc) {
 	return ctx, command(f)
 }
 
-const callAsm = `// {{ .cmdLit }} {{ .nameLit }} {{ .numArgsLit }}
-	// push return address
-	@{{ .returnLabel }}
-	D=A
-	@SP
-	A=M
-	M=D // *SP=return address
-	@SP
-	M=M+1 // SP++
-	// push LCL
-	@LCL
-	D=M
-	@SP
-	A=M
-	M=D // *SP=LCL
-	@SP
-	M=M+1 // SP++
-	// push ARG
-	@ARG
-	D=M
-	@SP
-	A=M
-	M=D
-	@SP
-	M=M+1 // SP++
-	// push THIS
-	@THIS
-	D=M
-	@SP
-	A=M
-	M=D
-	@SP
-	M=M+1 // SP++
-	// push THAT
-	@THAT
-	D=M
-	@SP
-	A=M
-	M=D
-	@SP
-	M=M+1
-	// set arg
-	@{{ .argDelta }}
-	D=A
-	@SP
-	D=M-D
-	@ARG
-	M=D
-	// set LCL
-	@SP
-	D=M
-	@LCL
-	M=D // LCL =SP
-
-	// goto {{ .nameLit }}
-	@{{ .functionLabel }}
-	0;JMP
-({{ .returnLabel }})
-`
-
-var callAsmTmpl *template.Template
-
-func init() {
-	callAsmTmpl = template.Must(template.New("callAsm").Parse(callAsm))
-}
-
 // Call implements the call command (call a function)
 type Call struct {
+	node
+
 	lit string
 
 	name string
@@ -194,21 +154,87 @@ func (c *Call) String() string {
 	return fmt.Sprintf("%s %s", CALL, c.name)
 }
 
+// Code returns the VM code of the command
+func (c *Call) Code() string {
+	return fmt.Sprintf("%s %s %s", c.lit, c.name, c.numArgsLit)
+}
+
+// Name returns the name of the called function
+func (c *Call) Name() string {
+	return c.name
+}
+
+// NumArgs returns the number of arguments
+func (c *Call) NumArgs() int {
+	return c.numArgs
+}
+
 // Translate creates the assembly to call a function
-func (c *Call) Translate(t *SymbolTable, wr io.Writer) error {
+func (c *Call) Translate(t *SymbolTable) ([]asm.Command, error) {
 	ft := t.FunctionTable(c.name)
+	returnLabel := ft.ReturnLabel()
+
+	b := &builder{}
+	b.comment(c.Code())
+	if t.sharedRoutines {
+		b.at(ft.FunctionLabel())
+		b.c("D", "A")
+		b.at("R13")
+		b.c("M", "D")
+		b.note("R13 = function")
+		b.at(c.numArgsLit)
+		b.c("D", "A")
+		b.at("R14")
+		b.c("M", "D")
+		b.note("R14 = nArgs")
+		b.at(returnLabel)
+		b.c("D", "A")
+		b.at("R15")
+		b.c("M", "D")
+		b.note("R15 = return address")
+		b.goTo(callRoutineLabel)
+		b.label(returnLabel)
+		return b.cmds, nil
+	}
 
-	data := map[string]interface{}{
-		"cmdLit":        c.lit,
-		"nameLit":       c.name,
-		"numArgsLit":    c.numArgsLit,
-		"returnLabel":   ft.ReturnLabel(),
-		"functionLabel": ft.FunctionLabel(),
-		"argDelta":      5 + c.numArgs,
+	b.comment("push return address")
+	b.at(returnLabel)
+	b.c("D", "A")
+	b.pushD()
+	b.pushFrame()
+	b.comment("set ARG")
+	b.atInt(5 + c.numArgs)
+	b.c("D", "A")
+	b.at("SP")
+	b.c("D", "M-D")
+	b.at("ARG")
+	b.c("M", "D")
+	b.note("ARG = SP - 5 - nArgs")
+	b.setLocal()
+	b.comment("goto " + c.name)
+	b.goTo(ft.FunctionLabel())
+	b.label(returnLabel)
+	return b.cmds, nil
+}
+
+// pushFrame saves the segment pointers of the caller
+func (b *builder) pushFrame() {
+	for _, seg := range []string{"LCL", "ARG", "THIS", "THAT"} {
+		b.comment("push " + seg)
+		b.at(seg)
+		b.c("D", "M")
+		b.pushD()
 	}
+}
 
-	err := callAsmTmpl.Execute(wr, data)
-	return err
+// setLocal sets LCL of the called function to SP
+func (b *builder) setLocal() {
+	b.comment("set LCL")
+	b.at("SP")
+	b.c("D", "M")
+	b.at("LCL")
+	b.c("M", "D")
+	b.note("LCL = SP")
 }
 
 func parseCall(p *Parser, ctx ParserContext) (ParserContext, stateFunc) {
@@ -221,10 +247,11 @@ func parseCall(p *Parser, ctx ParserContext) (ParserContext, stateFunc) {
 	}
 
 	c := &Call{
-		lit: lit,
+		node: node{pos: ctx.pos},
+		lit:  lit,
 	}
 
-	tok, lit, err = p.scanIgnore()
+	tok, lit, err = p.scanArg("function", c.lit)
 	if err != nil {
 		return ctx, parseError(err)
 	}
@@ -233,7 +260,7 @@ func parseCall(p *Parser, ctx ParserContext) (ParserContext, stateFunc) {
 	}
 	c.name = lit
 
-	tok, lit, err = p.scanIgnore()
+	tok, lit, err = p.scanArg("number of args", c.lit+" "+c.name)
 	if err != nil {
 		return ctx, parseError(err)
 	}
@@ -250,98 +277,103 @@ func parseCall(p *Parser, ctx ParserContext) (ParserContext, stateFunc) {
 	return ctx, command(c)
 }
 
-const returnAsm = `// {{ .cmdLit }}
-	@LCL
-	D=M
-	@R13
-	M=D // endFrame = LCL
-
-	@5
-	D=D-A
-	A=D
-	D=M
-	@R14
-	M=D // retAddr = *(endFrame -5)
-
-	@SP
-	M=M-1
-	A=M
-	D=M
-	@ARG
-	A=M
-	M=D // *ARG = pop()
-
-	@ARG
-	D=M+1
-	@SP
-	M=D // SP = ARG +1
-
-	@R13
-	D=M
-	D=D-1
-	A=D
-	D=M // *(endFrame - 1)
-	@THAT
-	M=D
-
-	@R13
-	D=M
-	@2
-	D=D-A
-	A=D
-	D=M // *(endFrame - 2)
-	@THIS
-	M=D
-
-	@R13
-	D=M
-	@3
-	D=D-A
-	A=D
-	D=M // *(endFrame - 3)
-	@ARG
-	M=D
-
-	@R13
-	D=M
-	@4
-	D=D-A
-	A=D
-	D=M // *(endFrame - 4)
-	@LCL
-	M=D
-
-	@R14
-	A=M
-	0;JMP
-`
-
-var returnAsmTmpl *template.Template
-
-func init() {
-	returnAsmTmpl = template.Must(template.New("returnAsm").Parse(returnAsm))
-}
-
 // Return implements the return command
 type Return struct {
+	node
+
 	lit string
 
 	function *Function
 }
 
+// NewReturn creates a return command of the function
+func NewReturn(function *Function) *Return {
+	return &Return{
+		lit: RETURN.Literal(),
+
+		function: function,
+	}
+}
+
 // String implements Stringer
 func (r *Return) String() string {
 	return RETURN.String()
 }
 
+// Code returns the VM code of the command
+func (r *Return) Code() string {
+	return r.lit
+}
+
+// Function returns the name of the function returned from
+func (r *Return) Function() string {
+	return functionName(r.function)
+}
+
 // Translate creates the assembly for the return command
-func (r *Return) Translate(t *SymbolTable, wr io.Writer) error {
-	data := map[string]string{
-		"cmdLit": r.lit,
+func (r *Return) Translate(t *SymbolTable) ([]asm.Command, error) {
+	b := &builder{}
+	b.comment(r.lit)
+	if t.sharedRoutines {
+		b.goTo(returnRoutineLabel)
+		return b.cmds, nil
+	}
+	b.ret()
+	return b.cmds, nil
+}
+
+// ret restores the frame of the caller and jumps to the return address
+func (b *builder) ret() {
+	b.at("LCL")
+	b.c("D", "M")
+	b.at("R13")
+	b.c("M", "D")
+	b.note("endFrame = LCL")
+
+	b.at("5")
+	b.c("D", "D-A")
+	b.c("A", "D")
+	b.c("D", "M")
+	b.at("R14")
+	b.c("M", "D")
+	b.note("retAddr = *(endFrame - 5)")
+
+	b.popD()
+	b.at("ARG")
+	b.c("A", "M")
+	b.c("M", "D")
+	b.note("*ARG = pop()")
+
+	b.at("ARG")
+	b.c("D", "M+1")
+	b.at("SP")
+	b.c("M", "D")
+	b.note("SP = ARG + 1")
+
+	b.at("R13")
+	b.c("D", "M")
+	b.c("D", "D-1")
+	b.c("A", "D")
+	b.c("D", "M")
+	b.at("THAT")
+	b.c("M", "D")
+	b.note("THAT = *(endFrame - 1)")
+	// THIS, ARG, LCL = *(endFrame - 2), *(endFrame - 3), *(endFrame - 4)
+	for i, seg := range []string{"THIS", "ARG", "LCL"} {
+		b.at("R13")
+		b.c("D", "M")
+		b.atInt(i + 2)
+		b.c("D", "D-A")
+		b.c("A", "D")
+		b.c("D", "M")
+		b.at(seg)
+		b.c("M", "D")
+		b.note(fmt.Sprintf("%s = *(endFrame - %d)", seg, i+2))
 	}
 
-	err := returnAsmTmpl.Execute(wr, data)
-	return err
+	b.at("R14")
+	b.c("A", "M")
+	b.jump("0", asm.JMP)
 }
 
 func parseReturn(p *Parser, ctx ParserContext) (ParserContext, stateFunc) {
@@ -357,6 +389,7 @@ func parseReturn(p *Parser, ctx ParserContext) (ParserContext, stateFunc) {
 	}
 
 	ret := &Return{
+		node:     node{pos: ctx.pos},
 		lit:      lit,
 		function: ctx.function,
 	}
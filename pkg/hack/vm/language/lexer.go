@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"strings"
 )
 
 // Token is a lexical token
@@ -183,11 +184,28 @@ func isDigit(ch rune) bool {
 	return ch >= '0' && ch <= '9'
 }
 
+// Pos is a position in a VM source file. Line and column start at 1.
+type Pos struct {
+	File string
+	Line int
+	Col  int
+}
+
+func (p Pos) String() string {
+	if p.File == "" {
+		return fmt.Sprintf("%d:%d", p.Line, p.Col)
+	}
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Col)
+}
+
 // Scanner can scan tokens
 type Scanner struct {
 	r *bufio.Reader
 
-	i int
+	line    int
+	col     int
+	prevCol int
+	last    rune
 }
 
 // NewScanner creates a new scanner, which reads from the given Reader r
@@ -195,24 +213,47 @@ func NewScanner(r io.Reader) *Scanner {
 	return &Scanner{
 		r: bufio.NewReader(r),
 
-		i: -1,
+		line: 1,
+		col:  1,
+	}
+}
+
+// Pos returns the position of the next character. The file is left empty.
+func (s *Scanner) Pos() Pos {
+	return Pos{
+		Line: s.line,
+		Col:  s.col,
 	}
 }
 
 func (s *Scanner) read() (rune, error) {
-	s.i++
 	ch, _, err := s.r.ReadRune()
 	if err != nil {
 		if err == io.EOF {
+			s.last = eof
 			return eof, nil
 		}
 		return eof, err
 	}
+	s.last = ch
+	s.prevCol = s.col
+	if ch == '\n' {
+		s.line++
+		s.col = 1
+	} else {
+		s.col++
+	}
 	return ch, nil
 }
 
 func (s *Scanner) unread() error {
-	s.i--
+	if s.last == eof {
+		return nil
+	}
+	if s.last == '\n' {
+		s.line--
+	}
+	s.col = s.prevCol
 	return s.r.UnreadRune()
 }
 
@@ -251,11 +292,7 @@ func (s *Scanner) Scan() (tok Token, lit string, err error) {
 			return ILLEGAL, "", err
 		}
 		if next != '/' {
-			return ILLEGAL, "", fmt.Errorf("invalid comment starting character / on index %d", s.i)
-		}
-		_, _, err = s.scanWhitespace()
-		if err != nil {
-			return ILLEGAL, "", err
+			return ILLEGAL, "", fmt.Errorf("invalid comment starting character /")
 		}
 		return s.scanComment()
 	}
@@ -344,84 +381,75 @@ func (s *Scanner) scanDigit() (tok Token, lit string, err error) {
 	return VALUE, buf.String(), nil
 }
 
+// scanComment scans the rest of the line. The line break is left for the
+// whitespace scanner.
 func (s *Scanner) scanComment() (tok Token, lit string, err error) {
 	var buf bytes.Buffer
-	ch, err := s.read()
-	if err != nil {
-		return ILLEGAL, "", err
-	}
-	buf.WriteRune(ch)
-
 	for {
 		if ch, err := s.read(); err != nil {
 			return ILLEGAL, "", err
-		} else if ch == eof || ch == '\n' || ch == '\r' {
+		} else if ch == eof {
+			break
+		} else if ch == '\n' || ch == '\r' {
+			err = s.unread()
+			if err != nil {
+				return ILLEGAL, "", err
+			}
 			break
 		} else {
 			buf.WriteRune(ch)
 		}
 	}
 
-	return COMMENT, buf.String(), nil
+	return COMMENT, strings.TrimSpace(buf.String()), nil
+}
+
+// keywords maps the VM language keywords to their tokens
+var keywords = map[string]Token{
+	"push": PUSH,
+	"pop":  POP,
+
+	"add": ADD,
+	"sub": SUB,
+	"neg": NEG,
+	"eq":  EQ,
+	"gt":  GT,
+	"lt":  LT,
+	"and": AND,
+	"or":  OR,
+	"not": NOT,
+
+	"constant": CONSTANT,
+	"static":   STATIC,
+	"local":    LCL,
+	"argument": ARG,
+	"this":     THIS,
+	"that":     THAT,
+	"temp":     TEMP,
+	"pointer":  POINTER,
+
+	"label":   LABEL,
+	"goto":    GOTO,
+	"if-goto": IFGOTO,
+
+	"function": FUNCTION,
+	"call":     CALL,
+	"return":   RETURN,
 }
 
 func mapIdent(str string) Token {
-	switch str {
-	case "push":
-		return PUSH
-	case "pop":
-		return POP
-
-	case "add":
-		return ADD
-	case "sub":
-		return SUB
-	case "neg":
-		return NEG
-	case "eq":
-		return EQ
-	case "gt":
-		return GT
-	case "lt":
-		return LT
-	case "and":
-		return AND
-	case "or":
-		return OR
-	case "not":
-		return NOT
-
-	case "constant":
-		return CONSTANT
-	case "static":
-		return STATIC
-	case "local":
-		return LCL
-	case "argument":
-		return ARG
-	case "this":
-		return THIS
-	case "that":
-		return THAT
-	case "temp":
-		return TEMP
-	case "pointer":
-		return POINTER
-
-	case "label":
-		return LABEL
-	case "goto":
-		return GOTO
-	case "if-goto":
-		return IFGOTO
-
-	case "function":
-		return FUNCTION
-	case "call":
-		return CALL
-	case "return":
-		return RETURN
+	if tok, ok := keywords[str]; ok {
+		return tok
 	}
-
 	return VALUE
 }
+
+// Literal returns the keyword of the token as written in VM code
+func (t Token) Literal() string {
+	for str, tok := range keywords {
+		if tok == t {
+			return str
+		}
+	}
+	return t.String()
+}
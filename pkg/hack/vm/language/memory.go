@@ -2,163 +2,10 @@ package language
 
 import (
 	"fmt"
-	"io"
 	"strconv"
-	"text/template"
-)
-
-// push constant i
-//
-// where .indexLit is the constant value to push on the stack
-const memoryPushConstant = `// {{ .cmdLit }} {{ .segLit }} {{ .indexLit }}
-	@{{ .indexLit }}
-	D=A
-	@SP
-	A=M // *SP
-	M=D // =i
-	@SP
-	M=M+1 // SP++
-`
-
-var memoryPushConstantTmpl *template.Template
-
-// push segment i
-//
-// where .segSymbol is one of LCL, ARG, THIS, THAT
-// and .indexLit being the index of the segment
-const memoryPushSegment = `// {{ .cmdLit }} {{ .segLit }} {{ .indexLit }}
-	@{{ .indexLit }}
-	D=A
-	@{{ .segSymbol }}
-	D=D+M
-	A=D
-	D=M // D = *({{ .segSymbol }} + i)
-	
-	@SP
-	A=M
-	M=D // *SP=*addr
-	@SP
-	M=M+1 // SP++
-`
-
-var memoryPushSegmentTmpl *template.Template
-
-const memoryPushTemp = `// {{ .cmdLit }} {{ .segLit }} {{ .indexLit }}
-	@{{ .indexLit }}
-	D=A
-	@{{ .segSymbol }}
-	D=D+A
-	A=D
-	D=M // D = *({{ .segSymbol }} + i)
-	
-	@SP
-	A=M
-	M=D // *SP=*addr
-	@SP
-	M=M+1 // SP++
-`
-
-var memoryPushTempTmpl *template.Template
-
-const memoryPushStatic = `// {{ .cmdLit }} {{ .segLit }} {{ .indexLit }}
-	@{{ .staticVar }}
-	D=M
-	
-	@SP
-	A=M
-	M=D // *SP=@{{ .staticVar }}
-	@SP
-	M=M+1 // SP++
-`
-
-var memoryPushStaticTmpl *template.Template
-
-const memoryPushPointer = `// {{ .cmdLit }} {{ .segLit }} {{ .indexLit }}
-	@{{ .segSymbol }}
-	D=M
-	
-	@SP
-	A=M
-	M=D
-	@SP
-	M=M+1
-`
-
-var memoryPushPointerTmpl *template.Template
-
-const memoryPopSegment = `// {{ .cmdLit }} {{ .segLit }} {{ .indexLit }}
-	@{{ .indexLit }}
-	D=A
-	@{{ .segSymbol}}
-	D=D+M
-	@R13
-	M=D // addr = {{ .segSymbol }} + {{ .indexLit}}
-	
-	@SP
-	M=M-1 // SP--
-	A=M
-	D=M // D=*SP
-	@R13
-	A=M
-	M=D // *addr=*SP
-`
-
-var memoryPopSegmentTmpl *template.Template
 
-const memoryPopTemp = `// {{ .cmdLit }} {{ .segLit }} {{ .indexLit }}
-	@{{ .indexLit }}
-	D=A
-	@{{ .segSymbol}}
-	D=D+A
-	@R13
-	M=D // addr = {{ .segSymbol }} + {{ .indexLit}}
-	
-	@SP
-	M=M-1 // SP--
-	A=M
-	D=M // D=*SP
-	@R13
-	A=M
-	M=D // *addr=*SP
-`
-
-var memoryPopTempTmpl *template.Template
-
-const memoryPopStatic = `// {{ .cmdLit }} {{ .segLit }} {{ .indexLit }}
-	@SP
-	M=M-1 // SP--
-	A=M
-	D=M // D=*SP
-	
-	@{{ .staticVar }}
-	M=D // @{{ .staticVar}} = D (*SP)
-`
-
-var memoryPopStaticTmpl *template.Template
-
-const memoryPopPointer = `// {{ .cmdLit }} {{ .segLit }} {{ .indexLit }}
-	@SP
-	M=M-1
-	A=M
-	D=M
-	
-	@{{ .segSymbol }}
-	M=D
-`
-
-var memoryPopPointerTmpl *template.Template
-
-func init() {
-	memoryPushConstantTmpl = template.Must(template.New("pushConstant").Parse(memoryPushConstant))
-	memoryPushSegmentTmpl = template.Must(template.New("pushSegment").Parse(memoryPushSegment))
-	memoryPushTempTmpl = template.Must(template.New("pushTemp").Parse(memoryPushTemp))
-	memoryPushStaticTmpl = template.Must(template.New("pushStatic").Parse(memoryPushStatic))
-	memoryPushPointerTmpl = template.Must(template.New("pushPointer").Parse(memoryPushPointer))
-	memoryPopSegmentTmpl = template.Must(template.New("popSegment").Parse(memoryPopSegment))
-	memoryPopTempTmpl = template.Must(template.New("popTemp").Parse(memoryPopTemp))
-	memoryPopStaticTmpl = template.Must(template.New("popStatic").Parse(memoryPopStatic))
-	memoryPopPointerTmpl = template.Must(template.New("popPointer").Parse(memoryPopPointer))
-}
+	asm "github.com/wongak/nand2tetris/pkg/hack/assembly/language"
+)
 
 type (
 	// Segment describes a memory segment to be accessed
@@ -171,6 +18,8 @@ type (
 
 	// MemoryAccess represents a memory access command
 	MemoryAccess struct {
+		node
+
 		accessComamnd Token // push or pop
 		lit           string
 		seg           Segment
@@ -179,70 +28,145 @@ type (
 	}
 )
 
+// NewMemoryAccess creates a push or pop command on the segment
+func NewMemoryAccess(accessCommand, seg Token, index int, file *File) *MemoryAccess {
+	indexLit := strconv.FormatInt(int64(index), 10)
+	return &MemoryAccess{
+		accessComamnd: accessCommand,
+		lit:           accessCommand.Literal(),
+		seg: Segment{
+			seg:      seg,
+			segLit:   seg.Literal(),
+			index:    index,
+			indexLit: indexLit,
+		},
+
+		file: file,
+	}
+}
+
 // String implementing Stringer
 func (m *MemoryAccess) String() string {
 	return fmt.Sprintf("%s %s %d", m.accessComamnd, m.seg.seg, m.seg.index)
 }
 
+// Code returns the VM code of the command
+func (m *MemoryAccess) Code() string {
+	return fmt.Sprintf("%s %s %s", m.lit, m.seg.segLit, m.seg.indexLit)
+}
+
+// Command returns the access command PUSH or POP
+func (m *MemoryAccess) Command() Token {
+	return m.accessComamnd
+}
+
+// Segment returns the accessed segment
+func (m *MemoryAccess) Segment() Token {
+	return m.seg.seg
+}
+
+// Index returns the index into the segment
+func (m *MemoryAccess) Index() int {
+	return m.seg.index
+}
+
+// File returns the name of the file the command was parsed from
+func (m *MemoryAccess) File() string {
+	if m.file == nil {
+		return ""
+	}
+	return m.file.name
+}
+
+// SourceFile returns the file the command was parsed from
+func (m *MemoryAccess) SourceFile() *File {
+	return m.file
+}
+
 // Translate translates the VM command to assembly
-func (m *MemoryAccess) Translate(t *SymbolTable, wr io.Writer) error {
+func (m *MemoryAccess) Translate(t *SymbolTable) ([]asm.Command, error) {
 	ft, err := t.FileTable(m.file.name)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	data := map[string]string{
-		"cmdLit":    m.lit,
-		"segLit":    m.seg.segLit,
-		"indexLit":  m.seg.indexLit,
-		"segSymbol": m.seg.seg.String(),
-	}
-	var tmpl *template.Template
+	b := &builder{}
+	b.comment(m.Code())
 	if m.accessComamnd == PUSH {
-		tmpl = memoryPushSegmentTmpl
-	} else {
-		tmpl = memoryPopSegmentTmpl
-	}
-	// TEMP segment on R5 - R12
-	if m.seg.seg == TEMP {
-		data["segSymbol"] = "R5"
-		if m.accessComamnd == PUSH {
-			tmpl = memoryPushTempTmpl
-		} else {
-			tmpl = memoryPopTempTmpl
-		}
-	}
-	// STATIC as assembly variable Filename.i
-	if m.seg.seg == STATIC {
-		data["staticVar"] = ft.Static(m.seg.index)
-		if m.accessComamnd == PUSH {
-			tmpl = memoryPushStaticTmpl
-		} else {
-			tmpl = memoryPopStaticTmpl
-		}
-	}
-	// CONSTANT
-	if m.seg.seg == CONSTANT {
-		tmpl = memoryPushConstantTmpl
-	}
-	// POINTER
-	if m.seg.seg == POINTER {
-		if m.seg.index == 0 {
-			data["segSymbol"] = "THIS"
-		} else {
-			data["segSymbol"] = "THAT"
+		switch m.seg.seg {
+		case CONSTANT:
+			b.at(m.seg.indexLit)
+			b.c("D", "A")
+		case STATIC:
+			// STATIC as assembly variable Filename.i
+			b.at(ft.Static(m.seg.index))
+			b.c("D", "M")
+		case POINTER:
+			b.at(pointerSymbol(m.seg.index))
+			b.c("D", "M")
+		case TEMP:
+			// TEMP segment on R5 - R12
+			b.at(m.seg.indexLit)
+			b.c("D", "A")
+			b.at("R5")
+			b.c("D", "D+A")
+			b.c("A", "D")
+			b.c("D", "M")
+			b.note("D = *(R5 + i)")
+		default:
+			b.at(m.seg.indexLit)
+			b.c("D", "A")
+			b.at(m.seg.seg.String())
+			b.c("D", "D+M")
+			b.c("A", "D")
+			b.c("D", "M")
+			b.note("D = *(seg + i)")
 		}
-		if m.accessComamnd == PUSH {
-			tmpl = memoryPushPointerTmpl
-		} else {
-			tmpl = memoryPopPointerTmpl
-		}
-	}
-	err = tmpl.Execute(wr, data)
-	if err != nil {
-		return err
+		b.pushD()
+		return b.cmds, nil
+	}
+
+	switch m.seg.seg {
+	case STATIC:
+		b.popD()
+		b.at(ft.Static(m.seg.index))
+		b.c("M", "D")
+		b.note(ft.Static(m.seg.index) + " = D")
+		return b.cmds, nil
+	case POINTER:
+		b.popD()
+		b.at(pointerSymbol(m.seg.index))
+		b.c("M", "D")
+		b.note(pointerSymbol(m.seg.index) + " = D")
+		return b.cmds, nil
+	case TEMP:
+		b.at(m.seg.indexLit)
+		b.c("D", "A")
+		b.at("R5")
+		b.c("D", "D+A")
+	default:
+		b.at(m.seg.indexLit)
+		b.c("D", "A")
+		b.at(m.seg.seg.String())
+		b.c("D", "D+M")
+	}
+	b.at("R13")
+	b.c("M", "D")
+	b.note("R13 = addr")
+	b.popD()
+	b.at("R13")
+	b.c("A", "M")
+	b.c("M", "D")
+	b.note("*addr = D")
+	return b.cmds, nil
+}
+
+// pointerSymbol returns the register of pointer 0 and 1
+func pointerSymbol(index int) string {
+	if index == 0 {
+		return "THIS"
 	}
-	return nil
+	return "THAT"
 }
 
 func parseMemoryAccess(p *Parser, ctx ParserContext) (ParserContext, stateFunc) {
@@ -254,6 +178,7 @@ func parseMemoryAccess(p *Parser, ctx ParserContext) (ParserContext, stateFunc)
 		return ctx, parseError(fmt.Errorf("invalid token %s (%s)", tok, lit))
 	}
 	cmd := &MemoryAccess{
+		node:          node{pos: ctx.pos},
 		accessComamnd: tok,
 		lit:           lit,
 
@@ -264,7 +189,7 @@ func parseMemoryAccess(p *Parser, ctx ParserContext) (ParserContext, stateFunc)
 
 func parseSegment(cmd *MemoryAccess) stateFunc {
 	return func(p *Parser, ctx ParserContext) (ParserContext, stateFunc) {
-		tok, lit, err := p.scanIgnore()
+		tok, lit, err := p.scanArg("segment", cmd.lit)
 		if err != nil {
 			return ctx, parseError(err)
 		}
@@ -292,7 +217,7 @@ func parseSegment(cmd *MemoryAccess) stateFunc {
 
 func parseSegmentIndex(cmd *MemoryAccess) stateFunc {
 	return func(p *Parser, ctx ParserContext) (ParserContext, stateFunc) {
-		tok, lit, err := p.scanIgnore()
+		tok, lit, err := p.scanArg("index", cmd.lit+" "+cmd.seg.segLit)
 		if err != nil {
 			return ctx, parseError(err)
 		}
@@ -303,6 +228,9 @@ func parseSegmentIndex(cmd *MemoryAccess) stateFunc {
 		if err != nil {
 			return ctx, parseError(fmt.Errorf("invalid value %s: %s", lit, err))
 		}
+		if cmd.seg.seg == CONSTANT && (i < 0 || i > asm.MaxAddress) {
+			return ctx, parseError(fmt.Errorf("constant %d out of range 0..%d", i, asm.MaxAddress))
+		}
 		cmd.seg.index = int(i)
 		cmd.seg.indexLit = lit
 
@@ -312,7 +240,7 @@ func parseSegmentIndex(cmd *MemoryAccess) stateFunc {
 
 func parsePointerIndex(cmd *MemoryAccess) stateFunc {
 	return func(p *Parser, ctx ParserContext) (ParserContext, stateFunc) {
-		tok, lit, err := p.scanIgnore()
+		tok, lit, err := p.scanArg("index", cmd.lit+" "+cmd.seg.segLit)
 		if err != nil {
 			return ctx, parseError(err)
 		}
@@ -3,26 +3,58 @@ package language
 import (
 	"fmt"
 	"io"
+	"strings"
 )
 
 // Parser is a hack VM language parser
 type Parser struct {
-	s   *Scanner
-	buf struct {
+	s    *Scanner
+	path string
+	buf  struct {
 		tok         Token
 		lit         string
+		pos         Pos
 		isUnscanned bool
 	}
 	i int
 
-	err  error
+	errs ErrorList
 	tree []Command
 }
 
+// Error is an error at a source position, formatted as file:line:col: message.
+// Errors on generated commands without position omit it.
+type Error struct {
+	Pos
+	Err error
+}
+
+func (e *Error) Error() string {
+	if e.Line == 0 {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%s: %v", e.Pos, e.Err)
+}
+
+// ErrorList is returned by Run and holds all errors of the input in
+// source order, one per line at most
+type ErrorList []*Error
+
+func (l ErrorList) Error() string {
+	msgs := make([]string, len(l))
+	for i, err := range l {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
 // ParserContext gives all states a context
 type ParserContext struct {
 	file     *File
 	function *Function
+
+	// pos is the position of the current command
+	pos Pos
 }
 
 // parser state machine
@@ -30,9 +62,16 @@ type stateFunc func(p *Parser, ctx ParserContext) (ParserContext, stateFunc)
 
 // NewParser creates a new parser on the given Reader r
 func NewParser(r io.Reader) *Parser {
+	return NewFileParser(r, "")
+}
+
+// NewFileParser creates a new parser on the given Reader r, which
+// reports positions in the file at path
+func NewFileParser(r io.Reader, path string) *Parser {
 	return &Parser{
-		s: NewScanner(r),
-		i: -1,
+		s:    NewScanner(r),
+		path: path,
+		i:    -1,
 
 		tree: make([]Command, 0),
 	}
@@ -45,8 +84,12 @@ func (p *Parser) scan() (tok Token, lit string, err error) {
 		return p.buf.tok, p.buf.lit, nil
 	}
 
+	p.buf.pos = p.s.Pos()
+	p.buf.pos.File = p.path
 	tok, lit, err = p.s.Scan()
 	if err != nil {
+		p.buf.tok = ILLEGAL
+		p.buf.lit = ""
 		return ILLEGAL, "", err
 	}
 
@@ -76,6 +119,37 @@ func (p *Parser) scanIgnore() (tok Token, lit string, err error) {
 	}
 }
 
+// scanArg scans the next argument of the command cmd. Arguments are on
+// the line of the command, so a line break, a comment or the end of the
+// input report the argument what as missing.
+func (p *Parser) scanArg(what, cmd string) (tok Token, lit string, err error) {
+	for {
+		tok, lit, err = p.scan()
+		if err != nil {
+			return ILLEGAL, "", err
+		}
+		switch {
+		case tok == WS && !strings.ContainsRune(lit, '\n'):
+			p.i--
+			continue
+		case tok == WS, tok == COMMENT, tok == EOF:
+			p.unscan()
+			return ILLEGAL, "", &missingError{what: what, cmd: cmd}
+		}
+		return
+	}
+}
+
+// missingError reports a missing argument of an incomplete command
+type missingError struct {
+	what string
+	cmd  string
+}
+
+func (e *missingError) Error() string {
+	return fmt.Sprintf("missing %s for %s", e.what, e.cmd)
+}
+
 // Run starts the parser
 func (p *Parser) Run(table *SymbolTable, fileName string) error {
 	ctx := ParserContext{
@@ -89,8 +163,8 @@ func (p *Parser) Run(table *SymbolTable, fileName string) error {
 	for state := top; state != nil; {
 		ctx, state = state(p, ctx)
 	}
-	if p.err != nil {
-		return fmt.Errorf("parse error on token %d (index %d): %v", p.i, p.s.i, p.err)
+	if len(p.errs) > 0 {
+		return p.errs
 	}
 	return nil
 }
@@ -100,11 +174,51 @@ func (p *Parser) Tree() []Command {
 	return p.tree
 }
 
+// parseError records the error on the last scanned token and recovers
 func parseError(err error) stateFunc {
 	return func(p *Parser, ctx ParserContext) (ParserContext, stateFunc) {
-		p.err = err
-		return ctx, nil
+		pos := p.buf.pos
+		if _, ok := err.(*missingError); ok {
+			// incomplete command, report on the command
+			pos = ctx.pos
+		}
+		p.errs = append(p.errs, &Error{
+			Pos: pos,
+			Err: err,
+		})
+		return ctx, recoverLine
+	}
+}
+
+// recoverLine skips the rest of the line of the invalid command and
+// continues parsing on the next line. Invalid input on the rest of the
+// line is not reported.
+func recoverLine(p *Parser, ctx ParserContext) (ParserContext, stateFunc) {
+	if !p.buf.isUnscanned && p.endsLine() {
+		return ctx, top
+	}
+	for {
+		before := p.s.Pos()
+		tok, _, err := p.scan()
+		switch {
+		case err != nil && p.s.Pos() == before:
+			// the input cannot be read any further
+			return ctx, nil
+		case tok == EOF:
+			return ctx, nil
+		case p.endsLine():
+			return ctx, top
+		}
+	}
+}
+
+// endsLine returns true if the last scanned token ends its line
+func (p *Parser) endsLine() bool {
+	if p.buf.tok == WS {
+		return strings.ContainsRune(p.buf.lit, '\n')
 	}
+	// invalid input may include the line break
+	return p.buf.tok == ILLEGAL && p.s.Pos().Line > p.buf.pos.Line
 }
 
 // top is the top level parser state machine
@@ -113,6 +227,7 @@ func top(p *Parser, ctx ParserContext) (ParserContext, stateFunc) {
 	if err != nil {
 		return ctx, parseError(err)
 	}
+	ctx.pos = p.buf.pos
 	switch true {
 	case tok == EOF:
 		return ctx, nil
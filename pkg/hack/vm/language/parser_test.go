@@ -18,7 +18,7 @@ push constant 23
 pop argument 1
 	`
 	p := language.NewParser(strings.NewReader(code))
-	_, err := p.Run("")
+	err := p.Run(language.NewSymbolTable(), "")
 	if err != nil {
 		t.Errorf("unexpected error on parse: %v", err)
 		return
@@ -32,7 +32,7 @@ func TestMemPopOnConstantReturnsError(t *testing.T) {
 pop constant 12
 	`
 	p := language.NewParser(strings.NewReader(code))
-	_, err := p.Run("")
+	err := p.Run(language.NewSymbolTable(), "")
 	if err == nil {
 		t.Error("expect error")
 		return
@@ -50,7 +50,7 @@ push constant 12
 push pointer 3
 	`
 	p := language.NewParser(strings.NewReader(code))
-	_, err := p.Run("")
+	err := p.Run(language.NewSymbolTable(), "")
 	if err == nil {
 		t.Error("expect error")
 		return
@@ -70,7 +70,7 @@ func execParserTestCase(t *testing.T, c parserTestCase) {
 	rd := strings.NewReader(c.input)
 	p := language.NewParser(rd)
 
-	_, err := p.Run("")
+	err := p.Run(language.NewSymbolTable(), "")
 	if err != nil {
 		t.Errorf("error on parsing: %v", err)
 		return
@@ -107,3 +107,151 @@ func TestLabelParser(t *testing.T) {
 	}
 	execParserTestCase(t, tst)
 }
+
+func TestParseErrorPos(t *testing.T) {
+	code := `// test
+push constant 1
+  pop  pointer 2
+`
+	p := language.NewFileParser(strings.NewReader(code), "dir/Test.vm")
+	err := p.Run(language.NewSymbolTable(), "Test")
+	if err == nil {
+		t.Error("expect error")
+		return
+	}
+	expect := "dir/Test.vm:3:16: invalid token VALUE (2). expect 0 or 1"
+	if err.Error() != expect {
+		t.Errorf("expect error %s, got %v", expect, err)
+	}
+}
+
+func TestConstantRange(t *testing.T) {
+	code := `push constant 32767
+push constant 40000
+`
+	p := language.NewFileParser(strings.NewReader(code), "Test.vm")
+	err := p.Run(language.NewSymbolTable(), "Test")
+	expect := "Test.vm:2:15: constant 40000 out of range 0..32767"
+	if err == nil || err.Error() != expect {
+		t.Errorf("expect error %s, got %v", expect, err)
+	}
+}
+
+func TestCommandPos(t *testing.T) {
+	code := `function Main.main 0
+	push constant 1 // one
+	if-goto END
+label END
+	return
+`
+	p := language.NewFileParser(strings.NewReader(code), "Main.vm")
+	err := p.Run(language.NewSymbolTable(), "Main")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	expect := []string{
+		"Main.vm:1:1",
+		"Main.vm:2:2",
+		"Main.vm:3:2",
+		"Main.vm:4:1",
+		"Main.vm:5:2",
+	}
+	cmds := p.Tree()
+	if len(cmds) != len(expect) {
+		t.Errorf("expect %d commands, got %d", len(expect), len(cmds))
+		return
+	}
+	for i, e := range expect {
+		if cmds[i].Pos().String() != e {
+			t.Errorf("expect %s at %s, got %s", cmds[i], e, cmds[i].Pos())
+		}
+	}
+}
+
+func TestParseErrorRecovery(t *testing.T) {
+	code := `function Main.main 0
+	push constant
+	add
+	pop pointer 2 // invalid pointer
+	//
+	push local 1
+	jump END
+	return
+`
+	p := language.NewFileParser(strings.NewReader(code), "Main.vm")
+	err := p.Run(language.NewSymbolTable(), "Main")
+	errs, ok := err.(language.ErrorList)
+	if !ok {
+		t.Errorf("expect error list, got %T %v", err, err)
+		return
+	}
+	expect := []string{
+		"Main.vm:2:2: missing index for push constant",
+		"Main.vm:4:14: invalid token VALUE (2). expect 0 or 1",
+		"Main.vm:7:2: invalid token VALUE (jump)",
+	}
+	if len(errs) != len(expect) {
+		t.Errorf("expect %d errors, got %d:\n%v", len(expect), len(errs), err)
+		return
+	}
+	for i, e := range expect {
+		if errs[i].Error() != e {
+			t.Errorf("expect error %q, got %q", e, errs[i].Error())
+		}
+	}
+	var code2 []string
+	for _, cmd := range p.Tree() {
+		code2 = append(code2, cmd.Code())
+	}
+	if strings.Join(code2, ";") != "function Main.main 0;add;push local 1;return" {
+		t.Errorf("unexpected commands %v", code2)
+	}
+}
+
+func TestParseIncomplete(t *testing.T) {
+	for _, c := range []struct {
+		code   string
+		errs   []string
+		parsed string
+	}{
+		{"pop local\nadd\n", []string{"1:1: missing index for pop local"}, "add"},
+		{"push // segment\nadd\n", []string{"1:1: missing segment for push"}, "add"},
+		{"function\nadd\n", []string{"1:1: missing name for function"}, "add"},
+		{"call Main.f\nadd\n", []string{"1:1: missing number of args for call Main.f"}, "add"},
+		{"add\n  goto", []string{"2:3: missing label for goto"}, "add"},
+		{
+			// the scanner error on the skipped line includes the line break
+			"push constant x /\npop local\nadd\n",
+			[]string{"1:15: invalid value x: strconv.ParseInt: parsing \"x\": invalid syntax", "2:1: missing index for pop local"},
+			"add",
+		},
+		{
+			"push constant x / y\nadd\npush / 1\nneg\n",
+			[]string{"1:15: invalid value x: strconv.ParseInt: parsing \"x\": invalid syntax", "3:6: invalid comment starting character /"},
+			"add;neg",
+		},
+	} {
+		p := language.NewParser(strings.NewReader(c.code))
+		err := p.Run(language.NewSymbolTable(), "Test")
+		errs, ok := err.(language.ErrorList)
+		if !ok {
+			t.Errorf("expect error list for %q, got %T %v", c.code, err, err)
+			continue
+		}
+		got := make([]string, len(errs))
+		for i, e := range errs {
+			got[i] = e.Error()
+		}
+		if strings.Join(got, "\n") != strings.Join(c.errs, "\n") {
+			t.Errorf("expect errors for %q\n%s\ngot\n%s", c.code, strings.Join(c.errs, "\n"), strings.Join(got, "\n"))
+		}
+		parsed := make([]string, 0)
+		for _, cmd := range p.Tree() {
+			parsed = append(parsed, cmd.Code())
+		}
+		if strings.Join(parsed, ";") != c.parsed {
+			t.Errorf("expect commands %s for %q, got %v", c.parsed, c.code, parsed)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package language
+
+import (
+	asm "github.com/wongak/nand2tetris/pkg/hack/assembly/language"
+)
+
+// labels of the shared routines. VM identifiers may not start with $,
+// so they do not clash with function names.
+const (
+	callRoutineLabel   = "$call"
+	returnRoutineLabel = "$return"
+)
+
+// compRoutineLabel returns the label of the shared comparison routine
+func compRoutineLabel(cmd Token) string {
+	return "$" + cmd.Literal()
+}
+
+// SharedRoutines returns the shared call, return and comparison routines.
+// They must be part of every program translated with UseSharedRoutines,
+// at a place which is not reached by falling through.
+//
+// The call routine expects the function address in R13, the number of
+// arguments in R14 and the return address in R15. The comparison
+// routines return to the address in R15.
+func SharedRoutines() []asm.Command {
+	b := &builder{}
+
+	b.comment("shared call routine")
+	b.label(callRoutineLabel)
+	b.comment("push return address")
+	b.at("R15")
+	b.c("D", "M")
+	b.pushD()
+	b.pushFrame()
+	b.comment("set ARG")
+	b.at("R14")
+	b.c("D", "M")
+	b.at("5")
+	b.c("D", "D+A")
+	b.at("SP")
+	b.c("D", "M-D")
+	b.at("ARG")
+	b.c("M", "D")
+	b.note("ARG = SP - 5 - nArgs")
+	b.setLocal()
+	b.at("R13")
+	b.c("A", "M")
+	b.jump("0", asm.JMP)
+	b.note("goto function")
+
+	b.comment("shared return routine")
+	b.label(returnRoutineLabel)
+	b.ret()
+
+	for _, cmd := range []Token{EQ, GT, LT} {
+		label := compRoutineLabel(cmd)
+		b.comment("shared " + cmd.Literal() + " routine")
+		b.label(label)
+		b.compare(cmd, label)
+		b.at("R15")
+		b.c("A", "M")
+		b.jump("0", asm.JMP)
+		b.note("return")
+	}
+	return b.cmds
+}
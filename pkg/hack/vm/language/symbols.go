@@ -2,6 +2,7 @@ package language
 
 import (
 	"fmt"
+	"sort"
 	"sync/atomic"
 )
 
@@ -10,9 +11,20 @@ type SymbolTable struct {
 	files               map[string]*fileTable
 	functionDefinitions map[string]struct{}
 	functions           map[string]*functionTable
+
+	sharedRoutines bool
+	compactStatics bool
+}
+
+// Static describes a static variable of a file and its assembly symbol
+type Static struct {
+	File   string
+	Index  int
+	Symbol string
 }
 
 type fileTable struct {
+	table    *SymbolTable
 	fileName string
 	static   *staticVars
 	// conditions
@@ -32,8 +44,9 @@ type staticVars struct {
 	j       int64
 }
 
-func newFileTable(fileName string) *fileTable {
+func newFileTable(table *SymbolTable, fileName string) *fileTable {
 	return &fileTable{
+		table:     table,
 		fileName:  fileName,
 		static:    newStaticVars(),
 		condIndex: -1,
@@ -56,9 +69,15 @@ func newStaticVars() *staticVars {
 	}
 }
 
-func (s *staticVars) index(index int) int64 {
+// index returns the symbol index of the static variable. Compacted
+// indices are numbered in order of first use.
+func (s *staticVars) index(index int, compact bool) int64 {
 	if _, ok := s.mapping[index]; !ok {
-		s.mapping[index] = atomic.AddInt64(&s.j, 1)
+		if compact {
+			s.mapping[index] = atomic.AddInt64(&s.j, 1)
+		} else {
+			s.mapping[index] = int64(index)
+		}
 	}
 	return s.mapping[index]
 }
@@ -72,12 +91,59 @@ func NewSymbolTable() *SymbolTable {
 	}
 }
 
+// UseSharedRoutines makes call, return and comparison commands jump to
+// the shared routines of SharedRoutines instead of inlining
+// their code
+func (t *SymbolTable) UseSharedRoutines(shared bool) {
+	t.sharedRoutines = shared
+}
+
+// SharedRoutines reports whether call, return and comparison commands
+// use the shared routines
+func (t *SymbolTable) SharedRoutines() bool {
+	return t.sharedRoutines
+}
+
+// UseCompactStatics numbers the static variables of each file in order
+// of first use instead of by their index, e.g. static 5 may become Foo.0
+// instead of Foo.5. The mode must be set before translation.
+func (t *SymbolTable) UseCompactStatics(compact bool) {
+	t.compactStatics = compact
+}
+
+// CompactStatics reports whether static variables are compacted
+func (t *SymbolTable) CompactStatics() bool {
+	return t.compactStatics
+}
+
+// Statics returns the static variables translated so far, sorted by file
+// and index
+func (t *SymbolTable) Statics() []Static {
+	statics := make([]Static, 0)
+	for _, f := range t.files {
+		for index, i := range f.static.mapping {
+			statics = append(statics, Static{
+				File:   f.fileName,
+				Index:  index,
+				Symbol: fmt.Sprintf("%s.%d", f.fileName, i),
+			})
+		}
+	}
+	sort.Slice(statics, func(i, j int) bool {
+		if statics[i].File != statics[j].File {
+			return statics[i].File < statics[j].File
+		}
+		return statics[i].Index < statics[j].Index
+	})
+	return statics
+}
+
 // RegisterFile registers a new file table
 func (t *SymbolTable) RegisterFile(fileName string) (*fileTable, error) {
 	if _, ok := t.files[fileName]; ok {
 		return nil, fmt.Errorf("file %s already registered", fileName)
 	}
-	t.files[fileName] = newFileTable(fileName)
+	t.files[fileName] = newFileTable(t, fileName)
 	return t.files[fileName], nil
 }
 
@@ -111,7 +177,7 @@ func (t *SymbolTable) FunctionTable(fName string) *functionTable {
 
 // Static returns the symbol for the static variable index i
 func (t *fileTable) Static(index int) string {
-	return fmt.Sprintf("%s.%d", t.fileName, t.static.index(index))
+	return fmt.Sprintf("%s.%d", t.fileName, t.static.index(index, t.table.compactStatics))
 }
 
 // Condition returns a condition label per symbol table
@@ -122,7 +188,7 @@ func (t *fileTable) Condition() string {
 // RegisterLabel registers a function scoped label
 func (t *functionTable) RegisterLabel(label string) error {
 	if _, ok := t.flabels[label]; ok {
-		return fmt.Errorf("label %s already registered.")
+		return fmt.Errorf("label %s already registered.", label)
 	}
 	t.flabels[label] = t.Label(label)
 	return nil
@@ -1,13 +1,16 @@
 package language_test
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/wongak/nand2tetris/pkg/hack/vm/language"
 )
 
 func TestSymbolStatic(t *testing.T) {
-	tbl, err := language.NewSymbolTable().RegisterFile("testFile")
+	table := language.NewSymbolTable()
+	table.UseCompactStatics(true)
+	tbl, err := table.RegisterFile("testFile")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -39,3 +42,33 @@ func TestSymbolStatic(t *testing.T) {
 		t.Errorf("expect symbol, %s got %s", expect, got)
 	}
 }
+
+func TestSymbolStaticSpec(t *testing.T) {
+	table := language.NewSymbolTable()
+	tbl, err := table.RegisterFile("Foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		index  int
+		expect string
+	}{
+		{5, "Foo.5"},
+		{0, "Foo.0"},
+		{5, "Foo.5"},
+	} {
+		got := tbl.Static(c.index)
+		if got != c.expect {
+			t.Errorf("expect symbol %s, got %s", c.expect, got)
+		}
+	}
+
+	statics := table.Statics()
+	expect := []language.Static{
+		{File: "Foo", Index: 0, Symbol: "Foo.0"},
+		{File: "Foo", Index: 5, Symbol: "Foo.5"},
+	}
+	if !reflect.DeepEqual(statics, expect) {
+		t.Errorf("expect statics %v, got %v", expect, statics)
+	}
+}
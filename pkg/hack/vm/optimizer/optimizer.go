@@ -0,0 +1,348 @@
+// Package optimizer rewrites parsed VM commands before translation.
+//
+// It evaluates arithmetic on constants at compile time, resolves
+// branches on constant conditions, removes push/pop pairs on the same
+// location and unreachable commands, and branches on comparisons instead
+// of their negation. Each rewrite translates to fewer instructions.
+// Rewrites stop at labels and function definitions, the only targets of
+// VM jumps and calls.
+package optimizer
+
+import (
+	"fmt"
+
+	"github.com/wongak/nand2tetris/pkg/hack/vm/language"
+)
+
+// rule rewrites the VM commands at the start of cmds in the current
+// function of o. It returns the number of commands rewritten and the new
+// commands, or 0 if it does not apply. New commands are placed at the
+// source position of the first rewritten command.
+type rule func(o *optimizer, cmds []language.Command) (int, []language.Command)
+
+var rules = []rule{
+	foldConstants,
+	constantBranch,
+	pushPop,
+	invertBranch,
+	deadCode,
+}
+
+type optimizer struct {
+	// function is the function of the current command
+	function *language.Function
+	// labels holds the used labels by function name
+	labels     map[string]map[string]bool
+	labelIndex int
+}
+
+// Optimize rewrites the commands of a VM file until no rule applies
+// anymore. The labels of inverted branches are unique in their function.
+func Optimize(cmds []language.Command) []language.Command {
+	o := &optimizer{
+		labels: make(map[string]map[string]bool),
+	}
+	for _, cmd := range cmds {
+		if l, ok := cmd.(*language.Label); ok {
+			o.usedLabels(l.Function())[l.Name()] = true
+		}
+	}
+	for {
+		out, changed := o.pass(cmds)
+		if !changed {
+			return out
+		}
+		cmds = out
+	}
+}
+
+func (o *optimizer) pass(cmds []language.Command) ([]language.Command, bool) {
+	o.function = nil
+	out := make([]language.Command, 0, len(cmds))
+	var changed bool
+	for i := 0; i < len(cmds); {
+		if f, ok := cmds[i].(*language.Function); ok {
+			o.function = f
+		}
+		n, repl := o.match(cmds[i:])
+		if n == 0 {
+			out = append(out, cmds[i])
+			i++
+			continue
+		}
+		out = append(out, repl...)
+		i += n
+		changed = true
+	}
+	return out, changed
+}
+
+func (o *optimizer) match(cmds []language.Command) (int, []language.Command) {
+	for _, r := range rules {
+		if n, repl := r(o, cmds); n > 0 {
+			return n, repl
+		}
+	}
+	return 0, nil
+}
+
+func (o *optimizer) usedLabels(function string) map[string]bool {
+	used, ok := o.labels[function]
+	if !ok {
+		used = make(map[string]bool)
+		o.labels[function] = used
+	}
+	return used
+}
+
+// label creates a label not used in the current function
+func (o *optimizer) label() *language.Label {
+	var function string
+	if o.function != nil {
+		function = o.function.Name()
+	}
+	used := o.usedLabels(function)
+	for {
+		name := fmt.Sprintf("NOT_TAKEN%d", o.labelIndex)
+		o.labelIndex++
+		if !used[name] {
+			used[name] = true
+			return language.NewLabel(name, o.function)
+		}
+	}
+}
+
+// constants evaluates the longest sequence of constant pushes and
+// arithmetic commands on their values. It returns the values left on
+// the stack and the number of commands evaluated.
+func constants(cmds []language.Command) ([]int16, int) {
+	stack := make([]int16, 0)
+	for n, cmd := range cmds {
+		switch c := cmd.(type) {
+		case *language.MemoryAccess:
+			if c.Command() != language.PUSH || c.Segment() != language.CONSTANT {
+				return stack, n
+			}
+			stack = append(stack, int16(c.Index()))
+		case *language.Arithmetic:
+			var ok bool
+			stack, ok = evaluate(c.Command(), stack)
+			if !ok {
+				return stack, n
+			}
+		default:
+			return stack, n
+		}
+	}
+	return stack, len(cmds)
+}
+
+func boolean(b bool) int16 {
+	if b {
+		return -1
+	}
+	return 0
+}
+
+// evaluate applies the arithmetic command to the stack. It returns false
+// if the stack holds too few operands.
+func evaluate(cmd language.Token, stack []int16) ([]int16, bool) {
+	switch cmd {
+	case language.NEG, language.NOT:
+		if len(stack) < 1 {
+			return stack, false
+		}
+		x := stack[len(stack)-1]
+		if cmd == language.NEG {
+			x = -x
+		} else {
+			x = ^x
+		}
+		return append(stack[:len(stack)-1], x), true
+	}
+
+	if len(stack) < 2 {
+		return stack, false
+	}
+	x, y := stack[len(stack)-2], stack[len(stack)-1]
+	var v int16
+	switch cmd {
+	case language.ADD:
+		v = x + y
+	case language.SUB:
+		v = x - y
+	case language.AND:
+		v = x & y
+	case language.OR:
+		v = x | y
+	case language.EQ:
+		v = boolean(x == y)
+	case language.GT:
+		v = boolean(x > y)
+	case language.LT:
+		v = boolean(x < y)
+	default:
+		return stack, false
+	}
+	return append(stack[:len(stack)-2], v), true
+}
+
+// pushConstants returns the shortest commands pushing the values in
+// place of the sequence starting with the constant push first. Negative
+// values are pushed as the complement of a constant.
+func pushConstants(first language.Command, values []int16) []language.Command {
+	file := first.(*language.MemoryAccess).SourceFile()
+	cmds := make([]language.Command, 0, len(values))
+	for _, v := range values {
+		if v >= 0 {
+			cmds = append(cmds, language.At(first.Pos(), language.NewMemoryAccess(language.PUSH, language.CONSTANT, int(v), file)))
+			continue
+		}
+		cmds = append(cmds,
+			language.At(first.Pos(), language.NewMemoryAccess(language.PUSH, language.CONSTANT, int(^v), file)),
+			language.At(first.Pos(), language.NewArithmetic(language.NOT, file)),
+		)
+	}
+	return cmds
+}
+
+// foldConstants replaces arithmetic on constants with its result:
+//
+//	push constant 2; push constant 3; add -> push constant 5
+func foldConstants(o *optimizer, cmds []language.Command) (int, []language.Command) {
+	stack, n := constants(cmds)
+	if n < 2 {
+		return 0, nil
+	}
+	repl := pushConstants(cmds[0], stack)
+	if len(repl) >= n {
+		return 0, nil
+	}
+	return n, repl
+}
+
+// constantBranch replaces if-goto on a constant condition with goto or
+// removes it:
+//
+//	push constant 0; not; if-goto L -> goto L
+//	push constant 0; if-goto L      ->
+func constantBranch(o *optimizer, cmds []language.Command) (int, []language.Command) {
+	stack, n := constants(cmds)
+	if n == 0 || n == len(cmds) || len(stack) == 0 {
+		return 0, nil
+	}
+	g, ok := cmds[n].(*language.IfGoto)
+	if !ok {
+		return 0, nil
+	}
+	cond := stack[len(stack)-1]
+	repl := pushConstants(cmds[0], stack[:len(stack)-1])
+	if cond != 0 {
+		repl = append(repl, language.At(cmds[0].Pos(), language.NewGoto(g.Label(), o.function)))
+	}
+	return n + 1, repl
+}
+
+// pushPop removes a push followed by a pop to the same location:
+//
+//	push local 0; pop local 0 ->
+func pushPop(o *optimizer, cmds []language.Command) (int, []language.Command) {
+	if len(cmds) < 2 {
+		return 0, nil
+	}
+	push, ok := cmds[0].(*language.MemoryAccess)
+	if !ok || push.Command() != language.PUSH {
+		return 0, nil
+	}
+	pop, ok := cmds[1].(*language.MemoryAccess)
+	if !ok || pop.Command() != language.POP {
+		return 0, nil
+	}
+	if push.Segment() != pop.Segment() || push.Index() != pop.Index() {
+		return 0, nil
+	}
+	if push.Segment() == language.STATIC && push.File() != pop.File() {
+		return 0, nil
+	}
+	return 2, []language.Command{}
+}
+
+func isComparison(cmd language.Command) bool {
+	a, ok := cmd.(*language.Arithmetic)
+	if !ok {
+		return false
+	}
+	switch a.Command() {
+	case language.EQ, language.GT, language.LT:
+		return true
+	}
+	return false
+}
+
+func isArithmetic(cmd language.Command, tok language.Token) bool {
+	a, ok := cmd.(*language.Arithmetic)
+	return ok && a.Command() == tok
+}
+
+// invertBranch removes the not of a negated comparison before if-goto
+// by branching over a goto instead:
+//
+//	lt; not; if-goto A                  -> lt; if-goto S; goto A; label S
+//	lt; not; if-goto A; goto B; label A -> lt; if-goto B; label A
+//
+// Only comparison results are inverted, since not x is non-zero for
+// every x but -1, not only for false.
+func invertBranch(o *optimizer, cmds []language.Command) (int, []language.Command) {
+	if len(cmds) < 3 || !isComparison(cmds[0]) || !isArithmetic(cmds[1], language.NOT) {
+		return 0, nil
+	}
+	g, ok := cmds[2].(*language.IfGoto)
+	if !ok {
+		return 0, nil
+	}
+	if len(cmds) >= 5 {
+		jump, isGoto := cmds[3].(*language.Goto)
+		l, isLabel := cmds[4].(*language.Label)
+		if isGoto && isLabel && l.Name() == g.Label() {
+			return 5, []language.Command{
+				cmds[0],
+				language.At(cmds[0].Pos(), language.NewIfGoto(jump.Label(), o.function)),
+				l,
+			}
+		}
+	}
+	skip := o.label()
+	return 3, []language.Command{
+		cmds[0],
+		language.At(cmds[0].Pos(), language.NewIfGoto(skip.Name(), o.function)),
+		language.At(cmds[0].Pos(), language.NewGoto(g.Label(), o.function)),
+		language.At(cmds[0].Pos(), skip),
+	}
+}
+
+// deadCode removes the commands after goto or return up to the next
+// label or function
+func deadCode(o *optimizer, cmds []language.Command) (int, []language.Command) {
+	switch cmds[0].(type) {
+	case *language.Goto, *language.Return:
+	default:
+		return 0, nil
+	}
+	n := 1
+	for n < len(cmds) && !isBlockStart(cmds[n]) {
+		n++
+	}
+	if n == 1 {
+		return 0, nil
+	}
+	return n, cmds[:1]
+}
+
+// isBlockStart returns true for commands which may be jumped to
+func isBlockStart(cmd language.Command) bool {
+	switch cmd.(type) {
+	case *language.Label, *language.Function:
+		return true
+	}
+	return false
+}
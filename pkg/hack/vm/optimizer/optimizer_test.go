@@ -0,0 +1,287 @@
+package optimizer_test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/wongak/nand2tetris/pkg/hack/vm/emulator"
+	"github.com/wongak/nand2tetris/pkg/hack/vm/language"
+	"github.com/wongak/nand2tetris/pkg/hack/vm/optimizer"
+)
+
+func parse(t *testing.T, src string) []language.Command {
+	p := language.NewParser(strings.NewReader(src))
+	err := p.Run(language.NewSymbolTable(), "Test")
+	if err != nil {
+		t.Fatalf("error on parse: %v", err)
+	}
+	return p.Tree()
+}
+
+// code returns the VM code of the commands on a single line
+func code(t *testing.T, cmds []language.Command) string {
+	buf := bytes.NewBuffer(nil)
+	err := language.WriteCode(buf, cmds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	for i := range lines {
+		lines[i] = strings.TrimSpace(lines[i])
+	}
+	return strings.Join(lines, "; ")
+}
+
+func TestOptimize(t *testing.T) {
+	for _, c := range []struct {
+		name, src, expect string
+	}{
+		{
+			"fold",
+			"push constant 2\npush constant 3\nadd\npush constant 4\nsub\n",
+			"push constant 1",
+		},
+		{
+			"fold negative",
+			"push constant 2\npush constant 3\nsub\npush constant 7\nadd\npush constant 10\nsub\n",
+			"push constant 3; not",
+		},
+		{
+			"fold comparison",
+			"push constant 2\npush constant 3\nlt\npush constant 2\npush constant 3\ngt\n",
+			"push constant 0; not; push constant 0",
+		},
+		{
+			"fold partial",
+			"push local 0\npush constant 2\npush constant 3\nadd\nadd\n",
+			"push local 0; push constant 5; add",
+		},
+		{
+			"keep short",
+			"push constant 5\nneg\npush constant 0\nnot\n",
+			"push constant 5; neg; push constant 0; not",
+		},
+		{
+			"constant branch",
+			"function Test.f 0\nlabel L\npush constant 0\nnot\nif-goto L\npush constant 0\nif-goto L\n",
+			"function Test.f 0; label L; goto L",
+		},
+		{
+			"push pop",
+			"push local 1\npop local 1\npush local 1\npop local 2\n",
+			"push local 1; pop local 2",
+		},
+		{
+			"invert",
+			"function Test.f 0\npush local 0\npush local 1\nlt\nnot\nif-goto END\npush constant 1\nlabel END\n",
+			"function Test.f 0; push local 0; push local 1; lt; if-goto NOT_TAKEN0; goto END; label NOT_TAKEN0; push constant 1; label END",
+		},
+		{
+			"invert fresh label",
+			"function Test.f 0\nlabel NOT_TAKEN0\npush local 0\npush local 1\neq\nnot\nif-goto NOT_TAKEN0\n",
+			"function Test.f 0; label NOT_TAKEN0; push local 0; push local 1; eq; if-goto NOT_TAKEN1; goto NOT_TAKEN0; label NOT_TAKEN1",
+		},
+		{
+			"invert over goto",
+			"function Test.f 0\npush local 0\npush local 1\ngt\nnot\nif-goto ELSE\ngoto THEN\nlabel ELSE\n",
+			"function Test.f 0; push local 0; push local 1; gt; if-goto THEN; label ELSE",
+		},
+		{
+			"keep non-boolean not",
+			"function Test.f 0\npush local 0\nnot\nif-goto L\nlabel L\n",
+			"function Test.f 0; push local 0; not; if-goto L; label L",
+		},
+		{
+			"dead code",
+			"function Test.f 0\ngoto L\npush constant 1\npop local 0\nlabel L\npush constant 0\nreturn\npush constant 1\nfunction Test.g 0\n",
+			"function Test.f 0; goto L; label L; push constant 0; return; function Test.g 0",
+		},
+	} {
+		got := code(t, optimizer.Optimize(parse(t, c.src)))
+		if got != c.expect {
+			t.Errorf("%s: expect\n%s\ngot\n%s", c.name, c.expect, got)
+		}
+	}
+}
+
+const program = `
+function Sys.init 0
+	push constant 10
+	call Main.sum 1
+	pop static 0
+	push constant 3
+	push constant 4
+	add
+	neg
+	push constant 100
+	call Main.max 2
+	pop static 1
+	push constant 100
+	neg
+	push constant 7
+	neg
+	call Main.max 2
+	pop static 2
+	push constant 5
+	call Main.count 1
+	pop static 3
+	push static 3
+	pop static 3
+label HALT
+	goto HALT
+	push constant 1
+
+// sum of 1..n
+function Main.sum 1
+	push constant 0
+	pop local 0
+label LOOP
+	push argument 0
+	push constant 0
+	gt
+	not
+	if-goto END
+	push local 0
+	push argument 0
+	add
+	pop local 0
+	push argument 0
+	push constant 1
+	sub
+	pop argument 0
+	goto LOOP
+	push constant 99
+	pop local 0
+label END
+	push local 0
+	return
+
+function Main.max 0
+	push argument 0
+	push argument 1
+	lt
+	not
+	if-goto FIRST
+	goto SECOND
+label FIRST
+	push argument 0
+	return
+label SECOND
+	push argument 1
+	return
+
+// counts down with a non-boolean condition
+function Main.count 1
+label LOOP
+	push argument 0
+	not
+	push constant 0
+	not
+	eq
+	if-goto END
+	push argument 0
+	push constant 1
+	sub
+	pop argument 0
+	push local 0
+	push constant 0
+	not
+	not
+	push constant 1
+	add
+	add
+	pop local 0
+	push constant 0
+	not
+	if-goto LOOP
+label END
+	push local 0
+	return
+`
+
+func run(t *testing.T, cmds []language.Command) *emulator.VM {
+	p, err := emulator.NewProgram(cmds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vm := emulator.New(p)
+	err = vm.Bootstrap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = vm.Run(100000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !vm.Halted() {
+		t.Fatal("expect program to halt")
+	}
+	return vm
+}
+
+func TestEquivalence(t *testing.T) {
+	cmds := parse(t, program)
+	optimized := optimizer.Optimize(cmds)
+	if len(optimized) >= len(cmds) {
+		t.Errorf("expect fewer commands, got %d of %d", len(optimized), len(cmds))
+	}
+
+	vm := run(t, cmds)
+	o := run(t, optimized)
+	t.Logf("%d commands in %d steps, %d in %d steps optimized", len(cmds), vm.Steps(), len(optimized), o.Steps())
+	if o.Steps() >= vm.Steps() {
+		t.Errorf("expect fewer steps, got %d of %d", o.Steps(), vm.Steps())
+	}
+	for i, expect := range []int16{55, 100, -7, 5} {
+		if vm.Static("Test", i) != expect {
+			t.Errorf("expect static %d = %d, got %d", i, expect, vm.Static("Test", i))
+		}
+		if o.Static("Test", i) != expect {
+			t.Errorf("expect optimized static %d = %d, got %d", i, expect, o.Static("Test", i))
+		}
+	}
+	if vm.RAM[emulator.SP] != o.RAM[emulator.SP] {
+		t.Errorf("expect SP %d, got %d", vm.RAM[emulator.SP], o.RAM[emulator.SP])
+	}
+}
+
+func TestOptimizePos(t *testing.T) {
+	src := `function Test.f 0
+label L
+push constant 2
+push constant 3
+sub
+push constant 1
+if-goto L
+label M
+push local 0
+push local 1
+lt
+not
+if-goto M
+`
+	out := optimizer.Optimize(parse(t, src))
+	got := make([]string, len(out))
+	for i, cmd := range out {
+		got[i] = fmt.Sprintf("%d %s", cmd.Pos().Line, cmd.Code())
+	}
+	expect := []string{
+		"1 function Test.f 0",
+		"2 label L",
+		"3 push constant 0",
+		"3 not",
+		"3 goto L",
+		"8 label M",
+		"9 push local 0",
+		"10 push local 1",
+		"11 lt",
+		"11 if-goto NOT_TAKEN0",
+		"11 goto M",
+		"11 label NOT_TAKEN0",
+	}
+	if strings.Join(got, "\n") != strings.Join(expect, "\n") {
+		t.Errorf("expect\n%s\ngot\n%s", strings.Join(expect, "\n"), strings.Join(got, "\n"))
+	}
+}
@@ -0,0 +1,163 @@
+package translator
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/wongak/nand2tetris/pkg/hack/vm/language"
+	"github.com/wongak/nand2tetris/pkg/hack/vm/optimizer"
+)
+
+// Inputs returns the .vm files of the arguments in translation order.
+// Arguments may be any mix of .vm files and directories containing .vm
+// files. Files given more than once are translated once.
+func Inputs(args []string) ([]string, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("expecting at least one argument. vm file or directory to translate")
+	}
+
+	seen := make(map[string]bool)
+	fileNames := make([]string, 0)
+	add := func(fileName string) {
+		fileName = filepath.Clean(fileName)
+		if seen[fileName] {
+			return
+		}
+		seen[fileName] = true
+		fileNames = append(fileNames, fileName)
+	}
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil {
+			return nil, fmt.Errorf("error on stat input: %v", err)
+		}
+		if !info.IsDir() {
+			if filepath.Ext(arg) != ".vm" {
+				return nil, fmt.Errorf("invalid input %s. expect .vm file or directory", arg)
+			}
+			add(arg)
+			continue
+		}
+		dirFiles, err := DirFiles(arg)
+		if err != nil {
+			return nil, err
+		}
+		if len(dirFiles) == 0 {
+			return nil, fmt.Errorf("no vm files in directory %s", arg)
+		}
+		for _, fileName := range dirFiles {
+			add(fileName)
+		}
+	}
+	SortFiles(fileNames)
+	return fileNames, nil
+}
+
+// Options configure the Build of a program
+type Options struct {
+	// Headless omits the bootstrap code and ends the program in an
+	// infinite loop instead
+	Headless bool
+	// Check runs the semantic check on the whole program before
+	// translation
+	Check bool
+	// Warn receives the calls to undefined functions found by the check.
+	// They are no errors, since the functions may be translated
+	// separately, e.g. the OS of Jack programs.
+	Warn io.Writer
+	// Optimize runs the VM optimizer on each file
+	Optimize bool
+	// Parse returns the commands of an input file and registers it in the
+	// symbol table. It defaults to ParseFile.
+	Parse func(fileName string) ([]language.Command, error)
+}
+
+// Build parses, checks and translates the files of a program in order.
+// The parse errors of all files are returned together.
+func (t *Translator) Build(fileNames []string, opts Options) error {
+	parse := opts.Parse
+	if parse == nil {
+		parse = t.ParseFile
+	}
+
+	errs := make(language.ErrorList, 0)
+	programs := make([][]language.Command, 0, len(fileNames))
+	for _, fileName := range fileNames {
+		cmds, err := parse(fileName)
+		if list, ok := err.(language.ErrorList); ok {
+			errs = append(errs, list...)
+			continue
+		}
+		if err != nil {
+			errs = append(errs, &language.Error{Err: err})
+			continue
+		}
+		programs = append(programs, cmds)
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+
+	if opts.Check {
+		all := make([]language.Command, 0)
+		for _, cmds := range programs {
+			all = append(all, cmds...)
+		}
+		if !opts.Headless {
+			// the bootstrap code calls Sys.init
+			all = append(all, language.NewCall("Sys.init", 0))
+		}
+		err := check(all, opts.Warn)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !opts.Headless {
+		err := t.Bootstrap()
+		if err != nil {
+			return fmt.Errorf("error writing bootstrap: %v", err)
+		}
+	}
+	for i, cmds := range programs {
+		if opts.Optimize {
+			cmds = optimizer.Optimize(cmds)
+		}
+		err := t.Translate(cmds)
+		if err != nil {
+			return fmt.Errorf("translation error on file %s: %v", fileNames[i], err)
+		}
+	}
+	if opts.Headless {
+		err := t.Halt()
+		if err != nil {
+			return fmt.Errorf("error writing halt: %v", err)
+		}
+	}
+	return nil
+}
+
+// check runs the semantic check and writes the calls to undefined
+// functions to warn
+func check(cmds []language.Command, warn io.Writer) error {
+	list, ok := language.Check(cmds).(language.ErrorList)
+	if !ok {
+		return nil
+	}
+	errs := make(language.ErrorList, 0, len(list))
+	for _, err := range list {
+		if _, ok := err.Err.(*language.UndefinedFunctionError); !ok {
+			errs = append(errs, err)
+			continue
+		}
+		if warn != nil {
+			fmt.Fprintf(warn, "warning: %v\n", err)
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package translator
+
+import (
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"sort"
+
+	asm "github.com/wongak/nand2tetris/pkg/hack/assembly/language"
+)
+
+// Source is the VM command translated to the instructions from ROM
+// address Addr up to End and the lines from AsmLine up to AsmEnd of the
+// assembly output. Both ranges are half-open, End and AsmEnd are the
+// address and line following the command. Code is empty for code
+// without VM command, e.g. the bootstrap code and the shared routines.
+// Locals is the number of local variables of a function command.
+type Source struct {
+	Addr     int    `json:"addr"`
+	End      int    `json:"end"`
+	AsmLine  int    `json:"asmLine"`
+	AsmEnd   int    `json:"asmEnd"`
+	File     string `json:"file,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Function string `json:"function,omitempty"`
+	Code     string `json:"code,omitempty"`
+	Locals   int    `json:"locals,omitempty"`
+}
+
+// source is a Source by the index of its first command in the program
+type source struct {
+	index int
+	Source
+}
+
+// SourceMap maps ROM addresses to the VM commands they were translated
+// from, sorted by address. Commands without instructions, e.g. labels,
+// share the address with the following command.
+type SourceMap []Source
+
+// SourceMap returns the source map of the program translated so far
+func (t *Translator) SourceMap() SourceMap {
+	m := make(SourceMap, 0, len(t.sources))
+	var addr, next int
+	// advance counts the instructions up to the program index
+	advance := func(index int) {
+		for ; next < index; next++ {
+			switch t.program[next].(type) {
+			case *asm.Label, *asm.Comment:
+			default:
+				addr++
+			}
+		}
+	}
+	for _, s := range t.sources {
+		advance(s.index)
+		if len(m) > 0 {
+			m[len(m)-1].End = addr
+			m[len(m)-1].AsmEnd = s.index + 1
+		}
+		src := s.Source
+		src.Addr = addr
+		// WriteCode writes one command per line
+		src.AsmLine = s.index + 1
+		m = append(m, src)
+	}
+	advance(len(t.program))
+	if len(m) > 0 {
+		m[len(m)-1].End = addr
+		m[len(m)-1].AsmEnd = len(t.program) + 1
+	}
+	return m
+}
+
+// sourceMapVersion is the version of the source map file format
+const sourceMapVersion = 1
+
+// WriteSourceMap writes the source map of the program as JSON. It
+// holds the format version and the sources in order of their address.
+func (t *Translator) WriteSourceMap(wr io.Writer) error {
+	enc := json.NewEncoder(wr)
+	enc.SetIndent("", "\t")
+	return enc.Encode(struct {
+		Version int       `json:"version"`
+		Sources SourceMap `json:"sources"`
+	}{
+		Version: sourceMapVersion,
+		Sources: t.SourceMap(),
+	})
+}
+
+// Lookup returns the source of the instruction at the ROM address
+func (m SourceMap) Lookup(addr int) (Source, bool) {
+	i := sort.Search(len(m), func(i int) bool {
+		return m[i].Addr > addr
+	})
+	if i == 0 {
+		return Source{}, false
+	}
+	return m[i-1], true
+}
+
+// Address returns the ROM address of the first command on the line of
+// the file. The file matches the path or its base name.
+func (m SourceMap) Address(file string, line int) (int, bool) {
+	for _, s := range m {
+		if s.Line != line || s.File == "" {
+			continue
+		}
+		if s.File == file || filepath.Base(s.File) == file {
+			return s.Addr, true
+		}
+	}
+	return 0, false
+}
+
+// IsCommand returns true if a VM command starts at the ROM address
+func (m SourceMap) IsCommand(addr int) bool {
+	i := sort.Search(len(m), func(i int) bool {
+		return m[i].Addr >= addr
+	})
+	for ; i < len(m) && m[i].Addr == addr; i++ {
+		if m[i].Code != "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,737 @@
+// BOOT
+	@256
+	D=A
+	@SP
+	M=D // SP = 256
+// call Sys.init 0
+// push return address
+	@Sys.init$ret.0
+	D=A
+	@SP
+	A=M
+	M=D // *SP = D
+	@SP
+	M=M+1 // SP++
+// push LCL
+	@LCL
+	D=M
+	@SP
+	A=M
+	M=D // *SP = D
+	@SP
+	M=M+1 // SP++
+// push ARG
+	@ARG
+	D=M
+	@SP
+	A=M
+	M=D // *SP = D
+	@SP
+	M=M+1 // SP++
+// push THIS
+	@THIS
+	D=M
+	@SP
+	A=M
+	M=D // *SP = D
+	@SP
+	M=M+1 // SP++
+// push THAT
+	@THAT
+	D=M
+	@SP
+	A=M
+	M=D // *SP = D
+	@SP
+	M=M+1 // SP++
+// set ARG
+	@5
+	D=A
+	@SP
+	D=M-D
+	@ARG
+	M=D // ARG = SP - 5 - nArgs
+// set LCL
+	@SP
+	D=M
+	@LCL
+	M=D // LCL = SP
+// goto Sys.init
+	@Sys.init
+	0;JMP
+(Sys.init$ret.0)
+// function Sys.init 0
+(Sys.init)
+// push constant 6
+	@6
+	D=A
+	@SP
+	A=M
+	M=D // *SP = D
+	@SP
+	M=M+1 // SP++
+// push constant 8
+	@8
+	D=A
+	@SP
+	A=M
+	M=D // *SP = D
+	@SP
+	M=M+1 // SP++
+// call Class1.set 2
+// push return address
+	@Class1.set$ret.0
+	D=A
+	@SP
+	A=M
+	M=D // *SP = D
+	@SP
+	M=M+1 // SP++
+// push LCL
+	@LCL
+	D=M
+	@SP
+	A=M
+	M=D // *SP = D
+	@SP
+	M=M+1 // SP++
+// push ARG
+	@ARG
+	D=M
+	@SP
+	A=M
+	M=D // *SP = D
+	@SP
+	M=M+1 // SP++
+// push THIS
+	@THIS
+	D=M
+	@SP
+	A=M
+	M=D // *SP = D
+	@SP
+	M=M+1 // SP++
+// push THAT
+	@THAT
+	D=M
+	@SP
+	A=M
+	M=D // *SP = D
+	@SP
+	M=M+1 // SP++
+// set ARG
+	@7
+	D=A
+	@SP
+	D=M-D
+	@ARG
+	M=D // ARG = SP - 5 - nArgs
+// set LCL
+	@SP
+	D=M
+	@LCL
+	M=D // LCL = SP
+// goto Class1.set
+	@Class1.set
+	0;JMP
+(Class1.set$ret.0)
+// pop temp 0
+	@0
+	D=A
+	@R5
+	D=D+A
+	@R13
+	M=D // R13 = addr
+	@SP
+	M=M-1 // SP--
+	A=M
+	D=M // D = *SP
+	@R13
+	A=M
+	M=D // *addr = D
+// push constant 23
+	@23
+	D=A
+	@SP
+	A=M
+	M=D // *SP = D
+	@SP
+	M=M+1 // SP++
+// push constant 15
+	@15
+	D=A
+	@SP
+	A=M
+	M=D // *SP = D
+	@SP
+	M=M+1 // SP++
+// call Class2.set 2
+// push return address
+	@Class2.set$ret.0
+	D=A
+	@SP
+	A=M
+	M=D // *SP = D
+	@SP
+	M=M+1 // SP++
+// push LCL
+	@LCL
+	D=M
+	@SP
+	A=M
+	M=D // *SP = D
+	@SP
+	M=M+1 // SP++
+// push ARG
+	@ARG
+	D=M
+	@SP
+	A=M
+	M=D // *SP = D
+	@SP
+	M=M+1 // SP++
+// push THIS
+	@THIS
+	D=M
+	@SP
+	A=M
+	M=D // *SP = D
+	@SP
+	M=M+1 // SP++
+// push THAT
+	@THAT
+	D=M
+	@SP
+	A=M
+	M=D // *SP = D
+	@SP
+	M=M+1 // SP++
+// set ARG
+	@7
+	D=A
+	@SP
+	D=M-D
+	@ARG
+	M=D // ARG = SP - 5 - nArgs
+// set LCL
+	@SP
+	D=M
+	@LCL
+	M=D // LCL = SP
+// goto Class2.set
+	@Class2.set
+	0;JMP
+(Class2.set$ret.0)
+// pop temp 0
+	@0
+	D=A
+	@R5
+	D=D+A
+	@R13
+	M=D // R13 = addr
+	@SP
+	M=M-1 // SP--
+	A=M
+	D=M // D = *SP
+	@R13
+	A=M
+	M=D // *addr = D
+// call Class1.get 0
+// push return address
+	@Class1.get$ret.0
+	D=A
+	@SP
+	A=M
+	M=D // *SP = D
+	@SP
+	M=M+1 // SP++
+// push LCL
+	@LCL
+	D=M
+	@SP
+	A=M
+	M=D // *SP = D
+	@SP
+	M=M+1 // SP++
+// push ARG
+	@ARG
+	D=M
+	@SP
+	A=M
+	M=D // *SP = D
+	@SP
+	M=M+1 // SP++
+// push THIS
+	@THIS
+	D=M
+	@SP
+	A=M
+	M=D // *SP = D
+	@SP
+	M=M+1 // SP++
+// push THAT
+	@THAT
+	D=M
+	@SP
+	A=M
+	M=D // *SP = D
+	@SP
+	M=M+1 // SP++
+// set ARG
+	@5
+	D=A
+	@SP
+	D=M-D
+	@ARG
+	M=D // ARG = SP - 5 - nArgs
+// set LCL
+	@SP
+	D=M
+	@LCL
+	M=D // LCL = SP
+// goto Class1.get
+	@Class1.get
+	0;JMP
+(Class1.get$ret.0)
+// call Class2.get 0
+// push return address
+	@Class2.get$ret.0
+	D=A
+	@SP
+	A=M
+	M=D // *SP = D
+	@SP
+	M=M+1 // SP++
+// push LCL
+	@LCL
+	D=M
+	@SP
+	A=M
+	M=D // *SP = D
+	@SP
+	M=M+1 // SP++
+// push ARG
+	@ARG
+	D=M
+	@SP
+	A=M
+	M=D // *SP = D
+	@SP
+	M=M+1 // SP++
+// push THIS
+	@THIS
+	D=M
+	@SP
+	A=M
+	M=D // *SP = D
+	@SP
+	M=M+1 // SP++
+// push THAT
+	@THAT
+	D=M
+	@SP
+	A=M
+	M=D // *SP = D
+	@SP
+	M=M+1 // SP++
+// set ARG
+	@5
+	D=A
+	@SP
+	D=M-D
+	@ARG
+	M=D // ARG = SP - 5 - nArgs
+// set LCL
+	@SP
+	D=M
+	@LCL
+	M=D // LCL = SP
+// goto Class2.get
+	@Class2.get
+	0;JMP
+(Class2.get$ret.0)
+// label WHILE
+(Sys.init$WHILE)
+// goto WHILE
+	@Sys.init$WHILE
+	0;JMP
+// function Class1.set 0
+(Class1.set)
+// push argument 0
+	@0
+	D=A
+	@ARG
+	D=D+M
+	A=D
+	D=M // D = *(seg + i)
+	@SP
+	A=M
+	M=D // *SP = D
+	@SP
+	M=M+1 // SP++
+// pop static 0
+	@SP
+	M=M-1 // SP--
+	A=M
+	D=M // D = *SP
+	@Class1.0
+	M=D // Class1.0 = D
+// push argument 1
+	@1
+	D=A
+	@ARG
+	D=D+M
+	A=D
+	D=M // D = *(seg + i)
+	@SP
+	A=M
+	M=D // *SP = D
+	@SP
+	M=M+1 // SP++
+// pop static 1
+	@SP
+	M=M-1 // SP--
+	A=M
+	D=M // D = *SP
+	@Class1.1
+	M=D // Class1.1 = D
+// push constant 0
+	@0
+	D=A
+	@SP
+	A=M
+	M=D // *SP = D
+	@SP
+	M=M+1 // SP++
+// return
+	@LCL
+	D=M
+	@R13
+	M=D // endFrame = LCL
+	@5
+	D=D-A
+	A=D
+	D=M
+	@R14
+	M=D // retAddr = *(endFrame - 5)
+	@SP
+	M=M-1 // SP--
+	A=M
+	D=M // D = *SP
+	@ARG
+	A=M
+	M=D // *ARG = pop()
+	@ARG
+	D=M+1
+	@SP
+	M=D // SP = ARG + 1
+	@R13
+	D=M
+	D=D-1
+	A=D
+	D=M
+	@THAT
+	M=D // THAT = *(endFrame - 1)
+	@R13
+	D=M
+	@2
+	D=D-A
+	A=D
+	D=M
+	@THIS
+	M=D // THIS = *(endFrame - 2)
+	@R13
+	D=M
+	@3
+	D=D-A
+	A=D
+	D=M
+	@ARG
+	M=D // ARG = *(endFrame - 3)
+	@R13
+	D=M
+	@4
+	D=D-A
+	A=D
+	D=M
+	@LCL
+	M=D // LCL = *(endFrame - 4)
+	@R14
+	A=M
+	0;JMP
+// function Class1.get 0
+(Class1.get)
+// push static 0
+	@Class1.0
+	D=M
+	@SP
+	A=M
+	M=D // *SP = D
+	@SP
+	M=M+1 // SP++
+// push static 1
+	@Class1.1
+	D=M
+	@SP
+	A=M
+	M=D // *SP = D
+	@SP
+	M=M+1 // SP++
+// sub
+	@SP
+	M=M-1 // SP--
+	A=M
+	D=M // D = *SP
+	@SP
+	M=M-1 // SP--
+	A=M
+	D=M-D
+	M=D
+	@SP
+	M=M+1 // SP++
+// return
+	@LCL
+	D=M
+	@R13
+	M=D // endFrame = LCL
+	@5
+	D=D-A
+	A=D
+	D=M
+	@R14
+	M=D // retAddr = *(endFrame - 5)
+	@SP
+	M=M-1 // SP--
+	A=M
+	D=M // D = *SP
+	@ARG
+	A=M
+	M=D // *ARG = pop()
+	@ARG
+	D=M+1
+	@SP
+	M=D // SP = ARG + 1
+	@R13
+	D=M
+	D=D-1
+	A=D
+	D=M
+	@THAT
+	M=D // THAT = *(endFrame - 1)
+	@R13
+	D=M
+	@2
+	D=D-A
+	A=D
+	D=M
+	@THIS
+	M=D // THIS = *(endFrame - 2)
+	@R13
+	D=M
+	@3
+	D=D-A
+	A=D
+	D=M
+	@ARG
+	M=D // ARG = *(endFrame - 3)
+	@R13
+	D=M
+	@4
+	D=D-A
+	A=D
+	D=M
+	@LCL
+	M=D // LCL = *(endFrame - 4)
+	@R14
+	A=M
+	0;JMP
+// function Class2.set 0
+(Class2.set)
+// push argument 0
+	@0
+	D=A
+	@ARG
+	D=D+M
+	A=D
+	D=M // D = *(seg + i)
+	@SP
+	A=M
+	M=D // *SP = D
+	@SP
+	M=M+1 // SP++
+// pop static 0
+	@SP
+	M=M-1 // SP--
+	A=M
+	D=M // D = *SP
+	@Class2.0
+	M=D // Class2.0 = D
+// push argument 1
+	@1
+	D=A
+	@ARG
+	D=D+M
+	A=D
+	D=M // D = *(seg + i)
+	@SP
+	A=M
+	M=D // *SP = D
+	@SP
+	M=M+1 // SP++
+// pop static 1
+	@SP
+	M=M-1 // SP--
+	A=M
+	D=M // D = *SP
+	@Class2.1
+	M=D // Class2.1 = D
+// push constant 0
+	@0
+	D=A
+	@SP
+	A=M
+	M=D // *SP = D
+	@SP
+	M=M+1 // SP++
+// return
+	@LCL
+	D=M
+	@R13
+	M=D // endFrame = LCL
+	@5
+	D=D-A
+	A=D
+	D=M
+	@R14
+	M=D // retAddr = *(endFrame - 5)
+	@SP
+	M=M-1 // SP--
+	A=M
+	D=M // D = *SP
+	@ARG
+	A=M
+	M=D // *ARG = pop()
+	@ARG
+	D=M+1
+	@SP
+	M=D // SP = ARG + 1
+	@R13
+	D=M
+	D=D-1
+	A=D
+	D=M
+	@THAT
+	M=D // THAT = *(endFrame - 1)
+	@R13
+	D=M
+	@2
+	D=D-A
+	A=D
+	D=M
+	@THIS
+	M=D // THIS = *(endFrame - 2)
+	@R13
+	D=M
+	@3
+	D=D-A
+	A=D
+	D=M
+	@ARG
+	M=D // ARG = *(endFrame - 3)
+	@R13
+	D=M
+	@4
+	D=D-A
+	A=D
+	D=M
+	@LCL
+	M=D // LCL = *(endFrame - 4)
+	@R14
+	A=M
+	0;JMP
+// function Class2.get 0
+(Class2.get)
+// push static 0
+	@Class2.0
+	D=M
+	@SP
+	A=M
+	M=D // *SP = D
+	@SP
+	M=M+1 // SP++
+// push static 1
+	@Class2.1
+	D=M
+	@SP
+	A=M
+	M=D // *SP = D
+	@SP
+	M=M+1 // SP++
+// sub
+	@SP
+	M=M-1 // SP--
+	A=M
+	D=M // D = *SP
+	@SP
+	M=M-1 // SP--
+	A=M
+	D=M-D
+	M=D
+	@SP
+	M=M+1 // SP++
+// return
+	@LCL
+	D=M
+	@R13
+	M=D // endFrame = LCL
+	@5
+	D=D-A
+	A=D
+	D=M
+	@R14
+	M=D // retAddr = *(endFrame - 5)
+	@SP
+	M=M-1 // SP--
+	A=M
+	D=M // D = *SP
+	@ARG
+	A=M
+	M=D // *ARG = pop()
+	@ARG
+	D=M+1
+	@SP
+	M=D // SP = ARG + 1
+	@R13
+	D=M
+	D=D-1
+	A=D
+	D=M
+	@THAT
+	M=D // THAT = *(endFrame - 1)
+	@R13
+	D=M
+	@2
+	D=D-A
+	A=D
+	D=M
+	@THIS
+	M=D // THIS = *(endFrame - 2)
+	@R13
+	D=M
+	@3
+	D=D-A
+	A=D
+	D=M
+	@ARG
+	M=D // ARG = *(endFrame - 3)
+	@R13
+	D=M
+	@4
+	D=D-A
+	A=D
+	D=M
+	@LCL
+	M=D // LCL = *(endFrame - 4)
+	@R14
+	A=M
+	0;JMP
@@ -0,0 +1,259 @@
+package translator
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	asm "github.com/wongak/nand2tetris/pkg/hack/assembly/language"
+	"github.com/wongak/nand2tetris/pkg/hack/vm/language"
+)
+
+// Translator translates VM programs of one or more files into a
+// single Hack assembly program
+type Translator struct {
+	table   *language.SymbolTable
+	program []asm.Command
+	sources []source
+	// function is the name of the function translated last
+	function string
+
+	// Log receives progress messages if set
+	Log io.Writer
+}
+
+// New creates a translator
+func New() *Translator {
+	return &Translator{
+		table:   language.NewSymbolTable(),
+		program: make([]asm.Command, 0),
+	}
+}
+
+// SymbolTable returns the symbol table shared by all files of the program
+func (t *Translator) SymbolTable() *language.SymbolTable {
+	return t.table
+}
+
+// Program returns the assembly program translated so far
+func (t *Translator) Program() []asm.Command {
+	return t.program
+}
+
+// WriteAsm writes the program as assembly code
+func (t *Translator) WriteAsm(wr io.Writer) error {
+	return asm.WriteCode(wr, t.program)
+}
+
+// WriteHack assembles the program and writes the machine code
+func (t *Translator) WriteHack(wr io.Writer) error {
+	_, err := t.Assemble(wr)
+	return err
+}
+
+// Assemble assembles the program, writes the machine code and returns the
+// symbol table with the addresses of the labels and variables
+func (t *Translator) Assemble(wr io.Writer) (*asm.SymbolTable, error) {
+	symbols := asm.NewSymbolTable()
+	err := asm.Assemble(symbols, t.program, wr)
+	if err != nil {
+		return nil, err
+	}
+	return symbols, nil
+}
+
+// emit appends the code translated from the source
+func (t *Translator) emit(src Source, code ...asm.Command) {
+	t.sources = append(t.sources, source{index: len(t.program), Source: src})
+	t.program = append(t.program, code...)
+}
+
+// UseCompactStatics numbers the static variables of each file in order
+// of first use instead of by their index
+func (t *Translator) UseCompactStatics() {
+	t.table.UseCompactStatics(true)
+}
+
+// WriteSymbols writes the symbol map of the static variables, one line
+// per variable with its file, index, assembly symbol and RAM address
+func (t *Translator) WriteSymbols(wr io.Writer) error {
+	symbols, err := t.Assemble(io.Discard)
+	if err != nil {
+		return err
+	}
+
+	naming := "spec"
+	if t.table.CompactStatics() {
+		naming = "compact"
+	}
+	_, err = fmt.Fprintf(wr, "// statics: %s\n// file index symbol address\n", naming)
+	if err != nil {
+		return err
+	}
+	for _, s := range t.table.Statics() {
+		addr, ok := symbols.Lookup(s.Symbol)
+		if !ok {
+			return fmt.Errorf("unresolved static symbol %s", s.Symbol)
+		}
+		_, err = fmt.Fprintf(wr, "%s %d %s %d\n", s.File, s.Index, s.Symbol, addr)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *Translator) logf(format string, args ...interface{}) {
+	if t.Log != nil {
+		fmt.Fprintf(t.Log, format, args...)
+	}
+}
+
+// UseSharedRoutines translates call, return and comparison commands to
+// jumps into shared routines, which are written by Bootstrap or Halt
+func (t *Translator) UseSharedRoutines() {
+	t.table.UseSharedRoutines(true)
+}
+
+// Bootstrap writes the bootstrap code, which initializes the stack
+// pointer and calls Sys.init
+func (t *Translator) Bootstrap() error {
+	t.emit(Source{},
+		&asm.Comment{Text: "BOOT"},
+		&asm.AInstruction{Address: "256"},
+		&asm.CInstruction{Dest: "D", Comp: "A", Jump: asm.NULL},
+		&asm.AInstruction{Address: "SP"},
+		&asm.CInstruction{Dest: "M", Comp: "D", Jump: asm.NULL, Comment: "SP = 256"},
+	)
+	err := t.Translate([]language.Command{language.NewCall("Sys.init", 0)})
+	if err != nil {
+		return err
+	}
+	// Sys.init does not return
+	t.sharedRoutines()
+	return nil
+}
+
+// Halt writes the terminating infinite loop for programs without
+// bootstrap
+func (t *Translator) Halt() error {
+	t.emit(Source{},
+		&asm.Comment{Text: "END"},
+		&asm.Label{Name: "END"},
+		&asm.AInstruction{Address: "END"},
+		&asm.CInstruction{Comp: "0", Jump: asm.JMP},
+	)
+	t.sharedRoutines()
+	return nil
+}
+
+func (t *Translator) sharedRoutines() {
+	if t.table.SharedRoutines() {
+		t.emit(Source{}, language.SharedRoutines()...)
+	}
+}
+
+// FileSymbol returns the name of the file as used for static variables,
+// i.e. the base name without extension
+func FileSymbol(fileName string) string {
+	fileBase := filepath.Base(fileName)
+	parts := strings.Split(fileBase, ".")
+	if len(parts) == 1 {
+		return fileBase
+	}
+	return strings.Join(parts[:len(parts)-1], ".")
+}
+
+// SortFiles sorts the file names into translation order. Sys.vm, the
+// entry point called by the bootstrap code, comes first, all other
+// files follow by name.
+func SortFiles(fileNames []string) {
+	sort.SliceStable(fileNames, func(i, j int) bool {
+		iSys := FileSymbol(fileNames[i]) == "Sys"
+		jSys := FileSymbol(fileNames[j]) == "Sys"
+		if iSys != jSys {
+			return iSys
+		}
+		return fileNames[i] < fileNames[j]
+	})
+}
+
+// DirFiles returns the .vm files of the directory in translation order
+func DirFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading dir: %v", err)
+	}
+	fileNames := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".vm" {
+			continue
+		}
+		fileNames = append(fileNames, filepath.Join(dir, e.Name()))
+	}
+	SortFiles(fileNames)
+	return fileNames, nil
+}
+
+// ParseFile parses the VM file and registers it in the symbol table
+func (t *Translator) ParseFile(fileName string) ([]language.Command, error) {
+	symbolTableFileName := FileSymbol(fileName)
+
+	in, err := os.Open(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("error opening vm file: %v", err)
+	}
+	defer in.Close()
+
+	t.logf("parsing %s...\n", symbolTableFileName)
+	p := language.NewFileParser(in, fileName)
+
+	err = p.Run(t.table, symbolTableFileName)
+	if err != nil {
+		return nil, err
+	}
+	return p.Tree(), nil
+}
+
+// TranslateFile parses and translates the VM file
+func (t *Translator) TranslateFile(fileName string) error {
+	cmds, err := t.ParseFile(fileName)
+	if err != nil {
+		return err
+	}
+	return t.Translate(cmds)
+}
+
+// Translate translates the commands. Their files must be registered in
+// the symbol table.
+func (t *Translator) Translate(cmds []language.Command) error {
+	for _, cmd := range cmds {
+		t.logf("  %+v\n", cmd)
+		var locals int
+		if f, ok := cmd.(*language.Function); ok {
+			t.function = f.Name()
+			locals = f.NumLocal()
+		}
+		code, err := cmd.Translate(t.table)
+		if err != nil {
+			if cmd.Pos().Line == 0 {
+				return fmt.Errorf("error translating %s: %v", cmd, err)
+			}
+			return &language.Error{
+				Pos: cmd.Pos(),
+				Err: fmt.Errorf("error translating %s: %v", cmd, err),
+			}
+		}
+		t.emit(Source{
+			File:     cmd.Pos().File,
+			Line:     cmd.Pos().Line,
+			Function: t.function,
+			Code:     cmd.Code(),
+			Locals:   locals,
+		}, code...)
+	}
+	return nil
+}
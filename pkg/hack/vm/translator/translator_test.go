@@ -0,0 +1,465 @@
+package translator_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	asm "github.com/wongak/nand2tetris/pkg/hack/assembly/language"
+	"github.com/wongak/nand2tetris/pkg/hack/cpu"
+	vm "github.com/wongak/nand2tetris/pkg/hack/vm/language"
+	"github.com/wongak/nand2tetris/pkg/hack/vm/translator"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func translateDir(t *testing.T, dir string) []byte {
+	fileNames, err := translator.DirFiles(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tr := translator.New()
+	err = tr.Bootstrap()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, fileName := range fileNames {
+		err = tr.TranslateFile(fileName)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	buf := bytes.NewBuffer(nil)
+	err = tr.WriteAsm(buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestTranslateDirGolden(t *testing.T) {
+	dir := filepath.Join("testdata", "StaticsTest")
+	out := translateDir(t, dir)
+	if !bytes.Equal(out, translateDir(t, dir)) {
+		t.Fatal("expect identical output for two runs")
+	}
+
+	golden := filepath.Join("testdata", "StaticsTest.asm")
+	if *update {
+		err := os.WriteFile(golden, out, 0644)
+		if err != nil {
+			t.Fatalf("error writing golden file: %v", err)
+		}
+	}
+	expect, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("error reading golden file: %v", err)
+	}
+	if !bytes.Equal(out, expect) {
+		t.Errorf("output differs from %s. run with -update if the change is intended", golden)
+	}
+}
+
+func TestWriteHack(t *testing.T) {
+	fileNames, err := translator.DirFiles(filepath.Join("testdata", "StaticsTest"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := translator.New()
+	err = tr.Bootstrap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fileName := range fileNames {
+		err = tr.TranslateFile(fileName)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	src := bytes.NewBuffer(nil)
+	err = tr.WriteAsm(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hack := bytes.NewBuffer(nil)
+	err = tr.WriteHack(hack)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(hack.Bytes(), assemble(t, src.Bytes())) {
+		t.Error("expect machine code to equal the assembled text output")
+	}
+}
+
+func TestWriteSymbols(t *testing.T) {
+	src := "push constant 1\npop static 5\npush constant 2\npop static 2\n"
+	for _, c := range []struct {
+		compact bool
+		expect  string
+	}{
+		{false, "// statics: spec\n// file index symbol address\nFoo 2 Foo.2 17\nFoo 5 Foo.5 16\n"},
+		{true, "// statics: compact\n// file index symbol address\nFoo 2 Foo.1 17\nFoo 5 Foo.0 16\n"},
+	} {
+		tr := translator.New()
+		if c.compact {
+			tr.UseCompactStatics()
+		}
+		p := vm.NewParser(strings.NewReader(src))
+		err := p.Run(tr.SymbolTable(), "Foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = tr.Translate(p.Tree())
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf := bytes.NewBuffer(nil)
+		err = tr.WriteSymbols(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != c.expect {
+			t.Errorf("expect symbols\n%s\ngot\n%s", c.expect, buf.String())
+		}
+	}
+}
+
+func TestSourceMap(t *testing.T) {
+	fileNames, err := translator.DirFiles(filepath.Join("testdata", "StaticsTest"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := translator.New()
+	err = tr.Bootstrap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fileName := range fileNames {
+		err = tr.TranslateFile(fileName)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	symbols, err := tr.Assemble(io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	labels := symbols.Labels()
+	m := tr.SourceMap()
+
+	src, ok := m.Lookup(0)
+	if !ok || src.Code != "" {
+		t.Errorf("expect bootstrap code without VM source, got %+v", src)
+	}
+	src, ok = m.Lookup(4)
+	if !ok || src.Code != "call Sys.init 0" || src.Line != 0 {
+		t.Errorf("expect bootstrap call of Sys.init, got %+v", src)
+	}
+
+	// function Sys.init has no instructions
+	addr, ok := m.Address("Sys.vm", 2)
+	if !ok || addr != labels["Sys.init"] {
+		t.Errorf("expect Sys.init at %d, got %d", labels["Sys.init"], addr)
+	}
+	src, ok = m.Lookup(addr)
+	if !ok || src.Code != "push constant 6" || src.Line != 3 || src.Function != "Sys.init" {
+		t.Errorf("expect push constant 6 on line 3, got %+v", src)
+	}
+	if !m.IsCommand(addr) || m.IsCommand(addr+1) {
+		t.Errorf("expect command to start at %d only", addr)
+	}
+
+	addr, ok = m.Address(filepath.Join("testdata", "StaticsTest", "Sys.vm"), 13)
+	if !ok || addr != labels["Sys.init$WHILE"] {
+		t.Errorf("expect label WHILE at %d, got %d", labels["Sys.init$WHILE"], addr)
+	}
+	src, _ = m.Lookup(labels["Class2.get"])
+	if src.Function != "Class2.get" || filepath.Base(src.File) != "Class2.vm" || src.Line != 12 {
+		t.Errorf("expect first command of Class2.get, got %+v", src)
+	}
+}
+
+func TestWriteSourceMap(t *testing.T) {
+	tr := translator.New()
+	err := tr.Bootstrap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fileNames, err := translator.DirFiles(filepath.Join("testdata", "StaticsTest"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fileName := range fileNames {
+		err = tr.TranslateFile(fileName)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	buf := bytes.NewBuffer(nil)
+	err = tr.WriteSourceMap(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m struct {
+		Version int
+		Sources []translator.Source
+	}
+	err = json.Unmarshal(buf.Bytes(), &m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Version != 1 || len(m.Sources) == 0 {
+		t.Fatalf("expect version 1 with sources, got %s", buf.String())
+	}
+
+	asmOut := bytes.NewBuffer(nil)
+	err = tr.WriteAsm(asmOut)
+	if err != nil {
+		t.Fatal(err)
+	}
+	asmLines := strings.Split(strings.TrimSuffix(asmOut.String(), "\n"), "\n")
+	hack := bytes.NewBuffer(nil)
+	err = tr.WriteHack(hack)
+	if err != nil {
+		t.Fatal(err)
+	}
+	instructions := strings.Count(hack.String(), "\n")
+
+	addr, line := 0, 1
+	for _, src := range m.Sources {
+		if src.Addr != addr || src.AsmLine != line || src.End < src.Addr || src.AsmEnd < src.AsmLine {
+			t.Fatalf("expect source to continue at %d line %d, got %+v", addr, line, src)
+		}
+		addr, line = src.End, src.AsmEnd
+		if src.Code != "" && asmLines[src.AsmLine-1] != "// "+src.Code {
+			t.Errorf("expect %s on line %d, got %s", src.Code, src.AsmLine, asmLines[src.AsmLine-1])
+		}
+	}
+	// the ends are exclusive
+	if addr != instructions || line != len(asmLines)+1 {
+		t.Errorf("expect sources up to %d and line %d, got %d and %d", instructions, len(asmLines)+1, addr, line)
+	}
+	src := m.Sources[len(m.Sources)-1]
+	if filepath.Base(src.File) != "Class2.vm" || src.Line == 0 || src.Function != "Class2.get" {
+		t.Errorf("expect last command in Class2.get, got %+v", src)
+	}
+}
+
+func TestSortFiles(t *testing.T) {
+	fileNames := []string{"b/Main.vm", "b/Sys.vm", "a/Memory.vm", "b/Array.vm"}
+	translator.SortFiles(fileNames)
+	expect := []string{"b/Sys.vm", "a/Memory.vm", "b/Array.vm", "b/Main.vm"}
+	if !reflect.DeepEqual(fileNames, expect) {
+		t.Errorf("expect %v, got %v", expect, fileNames)
+	}
+}
+
+func TestBuild(t *testing.T) {
+	dir := filepath.Join("testdata", "StaticsTest")
+	fileNames, err := translator.Inputs([]string{dir, filepath.Join(dir, "Sys.vm")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fileNames) != 3 {
+		t.Fatalf("expect 3 files, got %v", fileNames)
+	}
+	tr := translator.New()
+	err = tr.Build(fileNames, translator.Options{Check: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	buf := bytes.NewBuffer(nil)
+	err = tr.WriteAsm(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), translateDir(t, dir)) {
+		t.Error("expect build output to equal the translated directory")
+	}
+
+	// without Sys.vm and the OS the calls are undefined, which is no
+	// error
+	dir = t.TempDir()
+	fileName := filepath.Join(dir, "Main.vm")
+	err = os.WriteFile(fileName, []byte("function Main.main 0\npush constant 3\npush constant 4\ncall Math.multiply 2\ngoto END\nreturn\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	warn := bytes.NewBuffer(nil)
+	err = translator.New().Build([]string{fileName}, translator.Options{Check: true, Warn: warn})
+	if err == nil || err.Error() != fileName+":5:1: undefined label END in function Main.main" {
+		t.Errorf("expect undefined label, got %v", err)
+	}
+	expect := "warning: " + fileName + ":4:1: call to undefined function Math.multiply\nwarning: call to undefined function Sys.init\n"
+	if warn.String() != expect {
+		t.Errorf("expect warnings\n%s\ngot\n%s", expect, warn)
+	}
+	err = os.WriteFile(fileName, []byte("function Main.main 0\npush constant 3\npush constant 4\ncall Math.multiply 2\nreturn\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = translator.New().Build([]string{fileName}, translator.Options{Check: true})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func assemble(t *testing.T, src []byte) []byte {
+	p := asm.NewParser(bytes.NewReader(src))
+	err := p.Run()
+	if err != nil {
+		t.Fatalf("error on parse: %v", err)
+	}
+	buf := bytes.NewBuffer(nil)
+	err = asm.Assemble(asm.NewSymbolTable(), p.Tree(), buf)
+	if err != nil {
+		t.Fatalf("error on assemble: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func runProgram(t *testing.T, src []byte) *cpu.Machine {
+	return runHack(t, assemble(t, src))
+}
+
+func runTranslator(t *testing.T, tr *translator.Translator) *cpu.Machine {
+	buf := bytes.NewBuffer(nil)
+	err := tr.WriteHack(buf)
+	if err != nil {
+		t.Fatalf("error on assemble: %v", err)
+	}
+	return runHack(t, buf.Bytes())
+}
+
+func runHack(t *testing.T, hack []byte) *cpu.Machine {
+	m := cpu.NewMachine()
+	err := m.Load(bytes.NewReader(hack))
+	if err != nil {
+		t.Fatalf("error on load: %v", err)
+	}
+	_, err = m.Run(100000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.Halted() {
+		t.Fatal("expect machine to halt")
+	}
+	return m
+}
+
+func TestSharedRoutines(t *testing.T) {
+	dir := filepath.Join("testdata", "StaticsTest")
+	fileNames, err := translator.DirFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := translator.New()
+	tr.UseSharedRoutines()
+	err = tr.Bootstrap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fileName := range fileNames {
+		err = tr.TranslateFile(fileName)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m := runProgram(t, translateDir(t, dir))
+	s := runTranslator(t, tr)
+	t.Logf("%d instructions, %d with shared routines", m.Size(), s.Size())
+	if s.Size() >= m.Size() {
+		t.Errorf("expect shared routines to shrink the program")
+	}
+	if s.RAM[0] != m.RAM[0] || s.RAM[0] != 263 {
+		t.Errorf("expect SP 263, got %d", s.RAM[0])
+	}
+	// Class1.get and Class2.get
+	if s.RAM[261] != -2 || s.RAM[262] != 8 {
+		t.Errorf("expect -2 and 8 on the stack, got %d and %d", s.RAM[261], s.RAM[262])
+	}
+	for i := 16; i < 20; i++ {
+		if s.RAM[i] != m.RAM[i] {
+			t.Errorf("expect static RAM[%d] %d, got %d", i, m.RAM[i], s.RAM[i])
+		}
+	}
+}
+
+// pushValue returns VM code pushing any 16 bit value
+func pushValue(v int16) string {
+	switch {
+	case v == -32768:
+		return "push constant 32767\nneg\npush constant 1\nsub\n"
+	case v < 0:
+		return fmt.Sprintf("push constant %d\nneg\n", -v)
+	}
+	return fmt.Sprintf("push constant %d\n", v)
+}
+
+func TestComparisonOverflow(t *testing.T) {
+	values := []int16{0, 1, -1, 2, -2, 32767, -32767, -32768, 16384, -16385}
+	type comparison struct {
+		x, y int16
+		cmd  string
+	}
+	var cmps []comparison
+	var src strings.Builder
+	src.WriteString("function Sys.init 0\n")
+	for _, x := range values {
+		for _, y := range values {
+			for _, cmd := range []string{"eq", "gt", "lt"} {
+				src.WriteString(pushValue(x))
+				src.WriteString(pushValue(y))
+				src.WriteString(cmd + "\n")
+				cmps = append(cmps, comparison{x, y, cmd})
+			}
+		}
+	}
+	src.WriteString("label HALT\ngoto HALT\n")
+
+	for _, shared := range []bool{false, true} {
+		tr := translator.New()
+		if shared {
+			tr.UseSharedRoutines()
+		}
+		p := vm.NewParser(strings.NewReader(src.String()))
+		err := p.Run(tr.SymbolTable(), "Sys")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = tr.Bootstrap()
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = tr.Translate(p.Tree())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		m := runTranslator(t, tr)
+		base := 261 // after the frame of Sys.init
+		if int(m.RAM[0]) != base+len(cmps) {
+			t.Fatalf("expect SP %d, got %d", base+len(cmps), m.RAM[0])
+		}
+		for i, c := range cmps {
+			var expect int16
+			switch {
+			case c.cmd == "eq" && c.x == c.y,
+				c.cmd == "gt" && c.x > c.y,
+				c.cmd == "lt" && c.x < c.y:
+				expect = -1
+			}
+			if got := m.RAM[base+i]; got != expect {
+				t.Errorf("shared %t: expect %d %s %d = %d, got %d", shared, c.x, c.cmd, c.y, expect, got)
+			}
+		}
+	}
+}